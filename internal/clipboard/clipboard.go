@@ -0,0 +1,159 @@
+// Package clipboard copies codes to the clipboard and wipes them again.
+//
+// Codes go to the system clipboard tools (xclip, xsel, wl-copy, pbcopy, ...)
+// whenever they are installed, and additionally as an OSC 52 escape sequence
+// when attached to a terminal, which also works over SSH and inside tmux or
+// screen. Writing both covers terminals that silently drop OSC 52.
+package clipboard
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+	"github.com/leeineian/gauth/internal/model"
+)
+
+// DefaultClearAfter is how long a copied code stays on the clipboard.
+const DefaultClearAfter = 30 * time.Second
+
+var errUnavailable = errors.New("no clipboard available: not a terminal and no system clipboard tool found")
+
+// systemClipboard is the clipboard reached through the system tools.
+type systemClipboard interface {
+	Unsupported() bool
+	ReadAll() (string, error)
+	WriteAll(text string) error
+}
+
+type tools struct{}
+
+func (tools) Unsupported() bool          { return clipboard.Unsupported }
+func (tools) ReadAll() (string, error)   { return clipboard.ReadAll() }
+func (tools) WriteAll(text string) error { return clipboard.WriteAll(text) }
+
+// system and terminal are swapped out in tests. terminal returns nil when
+// OSC 52 can't be used.
+var (
+	system   systemClipboard = tools{}
+	terminal                 = func() io.Writer {
+		if useOSC52() {
+			return os.Stderr
+		}
+		return nil
+	}
+)
+
+// Copied remembers how a code was copied, so it can be wiped the same way.
+type Copied struct {
+	text   string
+	system bool
+	osc52  bool
+}
+
+// Copy puts text on the system clipboard and, on a terminal, sends it with
+// OSC 52 too. It only fails when neither worked.
+func Copy(text string) (*Copied, error) {
+	c := &Copied{text: text}
+	var errs []error
+
+	if !system.Unsupported() {
+		if err := system.WriteAll(text); err != nil {
+			errs = append(errs, err)
+		} else {
+			c.system = true
+		}
+	}
+	if w := terminal(); w != nil {
+		if _, err := sequence(osc52.New(text)).WriteTo(w); err != nil {
+			errs = append(errs, err)
+		} else {
+			c.osc52 = true
+		}
+	}
+
+	if !c.system && !c.osc52 {
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+		return nil, errUnavailable
+	}
+	return c, nil
+}
+
+// Clear wipes the copied code and reports whether it did. The system
+// clipboard is read back first and left alone if something else has been
+// copied since. OSC 52 copies can't be read back, so without a readable
+// system clipboard the terminal clipboard is wiped unconditionally, which
+// also discards anything the user copied after the code.
+func (c *Copied) Clear() (bool, error) {
+	if c.system {
+		current, err := system.ReadAll()
+		if err == nil {
+			if current != c.text {
+				return false, nil
+			}
+			if err := system.WriteAll(""); err != nil {
+				return false, err
+			}
+			if c.osc52 {
+				return true, c.clearTerminal()
+			}
+			return true, nil
+		}
+		// Unreadable, e.g. xclip without a display over SSH: rely on OSC 52
+	}
+
+	if !c.osc52 {
+		return false, nil
+	}
+	return true, c.clearTerminal()
+}
+
+func (c *Copied) clearTerminal() error {
+	w := terminal()
+	if w == nil {
+		return nil
+	}
+	_, err := sequence(osc52.Clear()).WriteTo(w)
+	return err
+}
+
+// ClearDelay returns how long to keep a code on the clipboard: the timeout,
+// cut short when a time-based code expires first. A zero timeout disables
+// clearing.
+func ClearDelay(timeout time.Duration, acc *model.Account, res *model.OTPResult) time.Duration {
+	if timeout <= 0 {
+		return 0
+	}
+	if acc.Type.IsTimeBased() {
+		if expiry := time.Duration(res.Remaining) * time.Second; expiry > 0 && expiry < timeout {
+			return expiry
+		}
+	}
+	return timeout
+}
+
+// useOSC52 reports whether stderr is a terminal that can receive OSC 52.
+func useOSC52() bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := os.Stderr.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// sequence wraps s for the terminal multiplexer we run in, if any.
+func sequence(s osc52.Sequence) osc52.Sequence {
+	switch {
+	case os.Getenv("TMUX") != "":
+		return s.Tmux()
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		return s.Screen()
+	}
+	return s
+}
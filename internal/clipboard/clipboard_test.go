@@ -0,0 +1,170 @@
+package clipboard
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	"github.com/leeineian/gauth/internal/model"
+)
+
+// fakeSystem is a system clipboard held in memory.
+type fakeSystem struct {
+	unsupported bool
+	content     string
+	readErr     error
+	writeErr    error
+}
+
+func (f *fakeSystem) Unsupported() bool { return f.unsupported }
+
+func (f *fakeSystem) ReadAll() (string, error) {
+	return f.content, f.readErr
+}
+
+func (f *fakeSystem) WriteAll(text string) error {
+	if f.writeErr != nil {
+		return f.writeErr
+	}
+	f.content = text
+	return nil
+}
+
+// fake swaps in sys and, when tty is set, a terminal that records what is
+// written to it.
+func fake(t *testing.T, sys *fakeSystem, tty bool) *bytes.Buffer {
+	t.Helper()
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM", "xterm")
+
+	var out bytes.Buffer
+	oldSystem, oldTerminal := system, terminal
+	t.Cleanup(func() { system, terminal = oldSystem, oldTerminal })
+
+	system = sys
+	terminal = func() io.Writer {
+		if tty {
+			return &out
+		}
+		return nil
+	}
+	return &out
+}
+
+func TestCopy(t *testing.T) {
+	failed := errors.New("no display")
+	tests := []struct {
+		name          string
+		sys           fakeSystem
+		tty           bool
+		system, osc52 bool
+		err           bool
+	}{
+		{name: "both", tty: true, system: true, osc52: true},
+		{name: "system only", system: true},
+		{name: "terminal only", sys: fakeSystem{unsupported: true}, tty: true, osc52: true},
+		{name: "system tool fails on a terminal", sys: fakeSystem{writeErr: failed}, tty: true, osc52: true},
+		{name: "system tool fails", sys: fakeSystem{writeErr: failed}, err: true},
+		{name: "nothing", sys: fakeSystem{unsupported: true}, err: true},
+	}
+
+	for _, tt := range tests {
+		sys := tt.sys
+		out := fake(t, &sys, tt.tty)
+
+		c, err := Copy("123456")
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Copy() error = %v", tt.name, err)
+		}
+		if c.system != tt.system || c.osc52 != tt.osc52 {
+			t.Errorf("%s: copied with system=%v osc52=%v, want %v and %v", tt.name, c.system, c.osc52, tt.system, tt.osc52)
+		}
+		if tt.system && sys.content != "123456" {
+			t.Errorf("%s: system clipboard holds %q", tt.name, sys.content)
+		}
+		if want := osc52.New("123456").String(); tt.osc52 && out.String() != want {
+			t.Errorf("%s: terminal got %q, want %q", tt.name, out.String(), want)
+		}
+	}
+}
+
+func TestClear(t *testing.T) {
+	tests := []struct {
+		name          string
+		sys           fakeSystem
+		tty           bool
+		copiedAfter   string // copied by someone else before clearing
+		cleared       bool
+		systemContent string
+		terminalClear bool
+	}{
+		{name: "system unchanged", cleared: true, systemContent: ""},
+		{name: "system changed", copiedAfter: "other", systemContent: "other"},
+		{name: "both unchanged", tty: true, cleared: true, systemContent: "", terminalClear: true},
+		{name: "both changed", tty: true, copiedAfter: "other", systemContent: "other"},
+		{name: "terminal only", sys: fakeSystem{unsupported: true}, tty: true, cleared: true, terminalClear: true},
+		{name: "system unreadable", sys: fakeSystem{readErr: errors.New("no display")}, tty: true, cleared: true, systemContent: "123456", terminalClear: true},
+	}
+
+	for _, tt := range tests {
+		sys := tt.sys
+		out := fake(t, &sys, tt.tty)
+
+		c, err := Copy("123456")
+		if err != nil {
+			t.Fatalf("%s: Copy() error = %v", tt.name, err)
+		}
+		if tt.copiedAfter != "" {
+			sys.content = tt.copiedAfter
+		}
+		out.Reset()
+
+		cleared, err := c.Clear()
+		if err != nil {
+			t.Fatalf("%s: Clear() error = %v", tt.name, err)
+		}
+		if cleared != tt.cleared {
+			t.Errorf("%s: cleared = %v, want %v", tt.name, cleared, tt.cleared)
+		}
+		if !sys.unsupported && sys.content != tt.systemContent {
+			t.Errorf("%s: system clipboard holds %q, want %q", tt.name, sys.content, tt.systemContent)
+		}
+		if got := out.String() == osc52.Clear().String(); got != tt.terminalClear {
+			t.Errorf("%s: terminal got %q, want a clear sequence: %v", tt.name, out.String(), tt.terminalClear)
+		}
+	}
+}
+
+func TestClearDelay(t *testing.T) {
+	totp := &model.Account{Type: model.TypeTOTP}
+	hotp := &model.Account{Type: model.TypeHOTP}
+
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		acc       *model.Account
+		remaining int64
+		want      time.Duration
+	}{
+		{"disabled", 0, totp, 10, 0},
+		{"code expires first", 30 * time.Second, totp, 10, 10 * time.Second},
+		{"timeout first", 30 * time.Second, totp, 45, 30 * time.Second},
+		{"hotp never expires", 30 * time.Second, hotp, 0, 30 * time.Second},
+		{"upper-case type", 30 * time.Second, &model.Account{Type: "TOTP"}, 5, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		got := ClearDelay(tt.timeout, tt.acc, &model.OTPResult{Remaining: tt.remaining})
+		if got != tt.want {
+			t.Errorf("%s: ClearDelay() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/leeineian/gauth/internal/clipboard"
+	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/output"
+	"github.com/leeineian/gauth/internal/service"
+	"github.com/leeineian/gauth/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+// findAccount resolves a query to a single account index. index is 1-based
+// and picks among the matches, so "--index 2" selects the second candidate
+// listed by an ambiguous query (or the second account in 'gauth list' when
+// the query is empty).
+func findAccount(accounts []model.Account, query string, exact bool, index int) (int, error) {
+	matches := service.Match(accounts, query, exact)
+	if len(matches) == 0 {
+		return -1, fmt.Errorf("no account matches %q", query)
+	}
+
+	if index > 0 {
+		if index > len(matches) {
+			return -1, fmt.Errorf("index %d out of range: %d accounts match", index, len(matches))
+		}
+		return matches[index-1], nil
+	}
+
+	if len(matches) > 1 {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%q matches %d accounts, refine the query or use --index:", query, len(matches))
+		for i, idx := range matches {
+			fmt.Fprintf(&b, "\n  %d) %s", i+1, accounts[idx].FullIdentifier())
+		}
+		return -1, fmt.Errorf("%s", b.String())
+	}
+	return matches[0], nil
+}
+
+var codeCmd = &cobra.Command{
+	Use:   "code [query]",
+	Short: "Print the current code of a single account",
+	Long: `Print the current code of the account matching query, and nothing else.
+
+The query is matched against issuer and label, fuzzily unless --exact is
+given. An ambiguous query fails and lists the candidates. HOTP counters are
+never advanced; use 'gauth next' for that.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var query string
+		if len(args) > 0 {
+			query = args[0]
+		}
+		opts, err := parseOutputFlags(cmd)
+		if err != nil {
+			return err
+		}
+		exact, _ := cmd.Flags().GetBool("exact")
+		index, _ := cmd.Flags().GetInt("index")
+		if query == "" && index == 0 {
+			return fmt.Errorf("a query or --index is required")
+		}
+
+		store, err := storage.NewStorage()
+		if err != nil {
+			return err
+		}
+
+		pwd, err := getOrPromptPassword(store)
+		if err != nil {
+			return err
+		}
+
+		accounts, err := store.ReadAccounts(pwd)
+		if err != nil {
+			return err
+		}
+
+		idx, err := findAccount(accounts, query, exact, index)
+		if err != nil {
+			return err
+		}
+
+		res, err := service.NewOTPService().Generate(&accounts[idx])
+		if err != nil {
+			return err
+		}
+
+		if opts.enabled() {
+			err = writeRecords(cmd.OutOrStdout(), opts, []output.Code{output.NewCode(&accounts[idx], res)})
+		} else {
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), res.Code)
+		}
+		if err != nil {
+			return err
+		}
+
+		if copyCode, _ := cmd.Flags().GetBool("copy"); copyCode {
+			return copyAndClear(&accounts[idx], res)
+		}
+		return nil
+	},
+}
+
+// copyAndClear copies the code and waits to wipe it again, either after
+// --clear-after or when the code expires. Interrupting clears immediately.
+func copyAndClear(acc *model.Account, res *model.OTPResult) error {
+	copied, err := clipboard.Copy(res.Code)
+	if err != nil {
+		return err
+	}
+
+	delay := clipboard.ClearDelay(clearAfter, acc, res)
+	if delay == 0 {
+		fmt.Fprintf(os.Stderr, "✓ Copied %s to the clipboard\n", acc.FullIdentifier())
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "✓ Copied %s to the clipboard, clearing in %s (Ctrl+C to clear now)\n", acc.FullIdentifier(), delay)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case <-ctx.Done():
+	case <-time.After(delay):
+	}
+
+	_, err = copied.Clear()
+	return err
+}
+
+func init() {
+	codeCmd.Flags().Bool("exact", false, "Only match a whole issuer, label or issuer:label")
+	codeCmd.Flags().Int("index", 0, "Pick the n-th matching account (1-based)")
+	codeCmd.Flags().BoolP("copy", "c", false, "Also copy the code to the clipboard")
+	codeCmd.Flags().DurationVar(&clearAfter, "clear-after", clipboard.DefaultClearAfter, "Wipe a copied code after this long or when it expires (0 keeps it). Without a readable system clipboard the OSC 52 copy is wiped even if something else was copied since")
+	addOutputFlags(codeCmd, "the code")
+	rootCmd.AddCommand(codeCmd)
+}
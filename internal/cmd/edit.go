@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/leeineian/gauth/internal/storage"
+	"github.com/leeineian/gauth/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var entryEditCmd = &cobra.Command{
+	Use:   "edit [query]",
+	Short: "Edit or rename an account",
+	Long: `Edit the account matching query, or pick one interactively.
+
+Without flags a form pre-filled with the current values is shown; the secret
+stays hidden and is only replaced when a new one is entered. With flags only
+the given fields change, for example:
+
+  gauth edit github --label work --period 60`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exact, _ := cmd.Flags().GetBool("exact")
+		index, _ := cmd.Flags().GetInt("index")
+		if secretStdin, _ := cmd.Flags().GetBool("secret-stdin"); secretStdin && len(args) == 0 && index == 0 {
+			return fmt.Errorf("--secret-stdin needs a query or --index to pick the account")
+		}
+
+		store, err := storage.NewStorage()
+		if err != nil {
+			return err
+		}
+		pwd, err := getOrPromptPassword(store)
+		if err != nil {
+			return err
+		}
+
+		accounts, err := store.ReadAccounts(pwd)
+		if err != nil {
+			return err
+		}
+
+		if len(accounts) == 0 {
+			fmt.Println("No accounts to edit.")
+			return nil
+		}
+
+		var idx int
+		if len(args) == 0 && index == 0 {
+			idx, err = ui.PromptSelectAccount("Select account to edit", accounts)
+			if err != nil {
+				return err
+			}
+			if idx < 0 || idx >= len(accounts) {
+				return nil // Cancelled
+			}
+		} else {
+			var query string
+			if len(args) > 0 {
+				query = args[0]
+			}
+			if idx, err = findAccount(accounts, query, exact, index); err != nil {
+				return err
+			}
+		}
+
+		// Drop a stored "Issuer:" prefix so renaming the issuer can't leave a
+		// stale one behind in the label
+		edited := accounts[idx]
+		edited.Label = edited.DisplayLabel()
+
+		if anyChanged(cmd, accountFlags) {
+			if err := applyAccountFlags(cmd, &edited); err != nil {
+				return err
+			}
+		} else {
+			acc, err := ui.PromptEditAccount(edited)
+			if err != nil {
+				return err
+			}
+			edited = *acc
+		}
+
+		edited.Normalize()
+		if err := edited.Validate(); err != nil {
+			return err
+		}
+
+		for i, a := range accounts {
+			if i != idx && a.FullIdentifier() == edited.FullIdentifier() {
+				return fmt.Errorf("account already exists: %s", edited.FullIdentifier())
+			}
+		}
+		printWarnings(&edited)
+
+		before := accounts[idx].FullIdentifier()
+		accounts[idx] = edited
+		if err := store.WriteAccounts(accounts, pwd); err != nil {
+			return err
+		}
+
+		if before != edited.FullIdentifier() {
+			fmt.Printf("✓ Renamed %s to %s\n", before, edited.FullIdentifier())
+		} else {
+			fmt.Printf("✓ Updated %s\n", edited.FullIdentifier())
+		}
+		return nil
+	},
+}
+
+func init() {
+	entryEditCmd.Flags().Bool("exact", false, "Only match a whole issuer, label or issuer:label")
+	entryEditCmd.Flags().Int("index", 0, "Pick the n-th matching account (1-based)")
+	addAccountFlags(entryEditCmd)
+	rootCmd.AddCommand(entryEditCmd)
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+func TestEditAccount(t *testing.T) {
+	github := model.Account{Issuer: "GitHub", Label: "GitHub:me", Secret: "JBSWY3DPEHPK3PXP", Type: model.TypeTOTP, Digits: 6, Algorithm: "sha1", Period: 30}
+	corp := model.Account{Issuer: "Corp", Label: "ops", Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Type: model.TypeHOTP, Digits: 6, Algorithm: "sha1", Counter: 5}
+
+	with := func(acc model.Account, change func(*model.Account)) model.Account {
+		change(&acc)
+		return acc
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		want  []model.Account
+		err   string
+	}{
+		{
+			name: "rename issuer drops the stored prefix", args: []string{"github", "--issuer", "Gitea"},
+			want: []model.Account{with(github, func(a *model.Account) { a.Issuer, a.Label = "Gitea", "me" }), corp},
+		},
+		{
+			name: "unset fields stay", args: []string{"corp", "--digits", "8"},
+			want: []model.Account{github, with(corp, func(a *model.Account) { a.Digits = 8 })},
+		},
+		{
+			name: "own identifier is not a duplicate", args: []string{"corp", "--issuer", "Corp", "--label", "ops"},
+			want: []model.Account{github, corp},
+		},
+		{
+			name: "secret from stdin", args: []string{"corp", "--secret-stdin"}, stdin: "KRSXG5CTMVRXEZLU\n",
+			want: []model.Account{github, with(corp, func(a *model.Account) { a.Secret = "KRSXG5CTMVRXEZLU" })},
+		},
+		{name: "duplicate", args: []string{"corp", "--issuer", "GitHub", "--label", "me"}, err: "already exists"},
+		{name: "stdin without a query", args: []string{"--secret-stdin"}, err: "needs a query"},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("HOME", t.TempDir())
+		store, err := storage.NewStorage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.WriteAccounts([]model.Account{github, corp}, ""); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd := &cobra.Command{}
+		cmd.Flags().Bool("exact", false, "")
+		cmd.Flags().Int("index", 0, "")
+		addAccountFlags(cmd)
+		cmd.SetIn(strings.NewReader(tt.stdin))
+		if err := cmd.ParseFlags(tt.args); err != nil {
+			t.Fatal(err)
+		}
+
+		err = entryEditCmd.RunE(cmd, cmd.Flags().Args())
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+
+		got, err := store.ReadAccounts("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\ngot  %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,25 +2,157 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
+	"github.com/charmbracelet/lipgloss"
+	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/provider/otpauth"
+	"github.com/leeineian/gauth/internal/service"
 	"github.com/leeineian/gauth/internal/storage"
 	"github.com/leeineian/gauth/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+// accountFlags are the flags that describe an account, shared by add and
+// edit. Setting any of them switches those commands to non-interactive mode.
+var accountFlags = []string{"issuer", "label", "secret", "secret-stdin", "type", "digits", "algorithm", "period", "counter"}
+
+// anyChanged reports whether any of the named flags was set.
+func anyChanged(cmd *cobra.Command, names []string) bool {
+	for _, name := range names {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// accountFromFlags builds an account from the command line, starting from
+// --uri when given and applying the explicit flags on top. It returns nil
+// when no account flags or name were passed.
+func accountFromFlags(cmd *cobra.Command, args []string) (*model.Account, error) {
+	flags := cmd.Flags()
+	if len(args) == 0 && !flags.Changed("uri") && !anyChanged(cmd, accountFlags) {
+		return nil, nil
+	}
+
+	acc := &model.Account{
+		Type:      model.DefaultType,
+		Digits:    model.DefaultDigits,
+		Algorithm: model.DefaultAlgo,
+		Period:    model.DefaultPeriod,
+		Counter:   model.DefaultCounter,
+	}
+	if uri, _ := flags.GetString("uri"); uri != "" {
+		parsed, err := otpauth.Parse(uri)
+		if err != nil {
+			return nil, err
+		}
+		acc = parsed
+	}
+
+	if len(args) > 0 {
+		if flags.Changed("label") {
+			return nil, fmt.Errorf("account name given both as argument and --label")
+		}
+		acc.Label = args[0]
+	}
+
+	if err := applyAccountFlags(cmd, acc); err != nil {
+		return nil, err
+	}
+
+	if acc.Secret == "" {
+		return nil, fmt.Errorf("a secret is required (use --secret-stdin, --secret or --uri)")
+	}
+	return acc, nil
+}
+
+// applyAccountFlags overwrites the fields of acc whose flags were set.
+func applyAccountFlags(cmd *cobra.Command, acc *model.Account) error {
+	flags := cmd.Flags()
+
+	if flags.Changed("issuer") {
+		acc.Issuer, _ = flags.GetString("issuer")
+	}
+	if flags.Changed("label") {
+		acc.Label, _ = flags.GetString("label")
+	}
+	if flags.Changed("type") {
+		t, _ := flags.GetString("type")
+		acc.Type = model.OTPType(t)
+	}
+	if flags.Changed("digits") {
+		acc.Digits, _ = flags.GetInt("digits")
+	}
+	if flags.Changed("algorithm") {
+		acc.Algorithm, _ = flags.GetString("algorithm")
+	}
+	if flags.Changed("period") {
+		acc.Period, _ = flags.GetInt64("period")
+	}
+	if flags.Changed("counter") {
+		acc.Counter, _ = flags.GetInt64("counter")
+	}
+
+	secretStdin, _ := flags.GetBool("secret-stdin")
+	if flags.Changed("secret") && secretStdin {
+		return fmt.Errorf("--secret and --secret-stdin are mutually exclusive")
+	}
+	if flags.Changed("secret") {
+		acc.Secret, _ = flags.GetString("secret")
+	}
+	if secretStdin {
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return fmt.Errorf("failed to read secret from stdin: %w", err)
+		}
+		acc.Secret = strings.TrimSpace(string(data))
+	}
+	return nil
+}
+
+// addAccountFlags registers accountFlags on cmd.
+func addAccountFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.String("issuer", "", "Provider name (e.g. GitHub)")
+	f.String("label", "", "Account name, usually a username or email")
+	f.String("secret", "", "Base32 secret (prefer --secret-stdin to keep it out of shell history)")
+	f.Bool("secret-stdin", false, "Read the Base32 secret from stdin")
+	f.String("type", string(model.DefaultType), "OTP type: totp, hotp or steam")
+	f.Int("digits", model.DefaultDigits, fmt.Sprintf("Code length (%d-%d)", model.MinDigits, model.MaxDigits))
+	f.String("algorithm", model.DefaultAlgo, "HMAC algorithm: sha1, sha256 or sha512")
+	f.Int64("period", model.DefaultPeriod, "TOTP period in seconds")
+	f.Int64("counter", model.DefaultCounter, "HOTP counter")
+}
+
 var entryAddCmd = &cobra.Command{
-	Use:   "add",
-	Short: "Add a new account interactively",
+	Use:   "add [name]",
+	Short: "Add a new account interactively or from flags",
+	Args:  cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		acc, err := ui.PromptNewAccount()
+		store, err := storage.NewStorage()
 		if err != nil {
 			return err
 		}
-
-		store, err := storage.NewStorage()
+		acc, err := accountFromFlags(cmd, args)
 		if err != nil {
 			return err
 		}
+		if acc == nil {
+			acc, err = ui.PromptNewAccount()
+			if err != nil {
+				return err
+			}
+		}
+
+		acc.Normalize()
+		if err := acc.Validate(); err != nil {
+			return err
+		}
+		printWarnings(acc)
 
 		pwd, err := getOrPromptPassword(store)
 		if err != nil {
@@ -93,3 +225,76 @@ var entryDeleteCmd = &cobra.Command{
 		return nil
 	},
 }
+
+var entryNextCmd = &cobra.Command{
+	Use:   "next",
+	Short: "Advance an HOTP account and show its next code",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := storage.NewStorage()
+		if err != nil {
+			return err
+		}
+
+		pwd, err := getOrPromptPassword(store)
+		if err != nil {
+			return err
+		}
+
+		accounts, err := store.ReadAccounts(pwd)
+		if err != nil {
+			return err
+		}
+
+		// Only HOTP accounts have a counter to advance
+		var hotp []model.Account
+		var indexes []int
+		for i, a := range accounts {
+			if a.Type.IsHOTP() {
+				hotp = append(hotp, a)
+				indexes = append(indexes, i)
+			}
+		}
+
+		if len(hotp) == 0 {
+			fmt.Println("No HOTP accounts found.")
+			return nil
+		}
+
+		selectedIdx, err := ui.PromptSelectAccount("Select HOTP account", hotp)
+		if err != nil {
+			return err
+		}
+
+		if selectedIdx < 0 || selectedIdx >= len(hotp) {
+			return nil // Cancelled
+		}
+
+		acc := &accounts[indexes[selectedIdx]]
+		res, err := service.NewOTPService().Advance(acc)
+		if err != nil {
+			return err
+		}
+
+		// Persist the new counter before revealing the code so it is never reused
+		if err := store.WriteAccounts(accounts, pwd); err != nil {
+			return err
+		}
+
+		fmt.Printf("%s (counter %d): %s\n", acc.FullIdentifier(), acc.Counter, res.Code)
+		return nil
+	},
+}
+
+var warningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+
+// printWarnings surfaces unusual but valid account parameters.
+func printWarnings(acc *model.Account) {
+	for _, w := range acc.Warnings() {
+		fmt.Fprintln(os.Stderr, warningStyle.Render(fmt.Sprintf("! %s: %s", acc.FullIdentifier(), w)))
+	}
+}
+
+func init() {
+	entryAddCmd.Flags().String("uri", "", "Add the account described by an otpauth:// URI")
+	addAccountFlags(entryAddCmd)
+}
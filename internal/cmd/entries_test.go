@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leeineian/gauth/internal/model"
+	"github.com/spf13/cobra"
+)
+
+// addFlagsCmd parses args with the flags of gauth add, feeding stdin to
+// --secret-stdin.
+func addFlagsCmd(t *testing.T, args []string, stdin string) (*cobra.Command, []string) {
+	t.Helper()
+	cmd := &cobra.Command{}
+	cmd.Flags().String("uri", "", "")
+	addAccountFlags(cmd)
+	cmd.SetIn(strings.NewReader(stdin))
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+	return cmd, cmd.Flags().Args()
+}
+
+func TestAccountFromFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		want  *model.Account // nil without err means interactive
+		err   string
+	}{
+		{name: "no flags", args: nil},
+		{
+			name: "name and stdin secret", args: []string{"GitHub:me", "--secret-stdin"}, stdin: "JBSWY3DPEHPK3PXP\n",
+			want: &model.Account{Label: "GitHub:me", Secret: "JBSWY3DPEHPK3PXP", Type: model.TypeTOTP, Digits: 6, Algorithm: "sha1", Period: 30},
+		},
+		{
+			name: "explicit fields", args: []string{"me", "--issuer", "Corp", "--secret", "JBSWY3DPEHPK3PXP", "--type", "hotp", "--counter", "5", "--digits", "8"},
+			want: &model.Account{Issuer: "Corp", Label: "me", Secret: "JBSWY3DPEHPK3PXP", Type: model.TypeHOTP, Digits: 8, Algorithm: "sha1", Period: 30, Counter: 5},
+		},
+		{
+			name: "uri with overrides", args: []string{"--uri", "otpauth://totp/GitHub:me?secret=JBSWY3DPEHPK3PXP&period=60", "--label", "work"},
+			want: &model.Account{Issuer: "GitHub", Label: "work", Secret: "JBSWY3DPEHPK3PXP", Type: model.TypeTOTP, Digits: 6, Algorithm: "sha1", Period: 60},
+		},
+		{name: "name and label", args: []string{"me", "--label", "you", "--secret", "JBSWY3DPEHPK3PXP"}, err: "both as argument and --label"},
+		{name: "two secrets", args: []string{"me", "--secret", "JBSWY3DPEHPK3PXP", "--secret-stdin"}, err: "mutually exclusive"},
+		{name: "no secret", args: []string{"me", "--issuer", "Corp"}, err: "a secret is required"},
+	}
+
+	for _, tt := range tests {
+		cmd, args := addFlagsCmd(t, tt.args, tt.stdin)
+		got, err := accountFromFlags(cmd, args)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+			continue
+		}
+		if got != nil && (got.Issuer != tt.want.Issuer || got.Label != tt.want.Label || got.Secret != tt.want.Secret ||
+			got.Type != tt.want.Type || got.Digits != tt.want.Digits || got.Algorithm != tt.want.Algorithm ||
+			got.Period != tt.want.Period || got.Counter != tt.want.Counter) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, *got, *tt.want)
+		}
+	}
+}
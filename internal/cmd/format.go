@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/leeineian/gauth/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// outputOptions holds the -o and --format flags of a command. The zero
+// value means the regular styled table.
+type outputOptions struct {
+	format output.Format
+	tmpl   *template.Template
+}
+
+func (o outputOptions) enabled() bool {
+	return o.format != "" || o.tmpl != nil
+}
+
+// parseOutputFlags reads -o and --format, which are mutually exclusive.
+func parseOutputFlags(cmd *cobra.Command) (outputOptions, error) {
+	var opts outputOptions
+	name, _ := cmd.Flags().GetString("output")
+	text, _ := cmd.Flags().GetString("format")
+	if name != "" && text != "" {
+		return opts, fmt.Errorf("--output and --format are mutually exclusive")
+	}
+
+	if name != "" {
+		f, err := output.ParseFormat(name)
+		if err != nil {
+			return opts, err
+		}
+		opts.format = f
+	}
+	if text != "" {
+		t, err := output.ParseTemplate(text)
+		if err != nil {
+			return opts, err
+		}
+		opts.tmpl = t
+	}
+	return opts, nil
+}
+
+func writeRecords[T output.Record](w io.Writer, opts outputOptions, records []T) error {
+	if opts.tmpl != nil {
+		return output.WriteTemplate(w, opts.tmpl, records)
+	}
+	return output.Write(w, opts.format, records)
+}
+
+// addOutputFlags registers -o and --format on cmd and documents the
+// template fields in its help.
+func addOutputFlags(cmd *cobra.Command, what string) {
+	cmd.Flags().StringP("output", "o", "", "Print "+what+" as "+outputFormats())
+	cmd.Flags().String("format", "", "Print "+what+" with a Go template, e.g. '{{.Issuer}}/{{.Label}}'")
+
+	if cmd.Long == "" {
+		cmd.Long = cmd.Short + "."
+	}
+	cmd.Long += "\n\n" + output.TemplateHelp
+}
+
+// outputFormats describes the -o choices for help texts.
+func outputFormats() string {
+	names := make([]string, len(output.Formats))
+	for i, f := range output.Formats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ", ")
+}
@@ -1,20 +1,111 @@
 package cmd
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
-	"github.com/leeineian/gauth/internal/provider/andotp"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/leeineian/gauth/internal/provider"
+	"github.com/leeineian/gauth/internal/service"
 	"github.com/leeineian/gauth/internal/storage"
 	"github.com/leeineian/gauth/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+var statusStyles = map[service.ImportStatus]lipgloss.Style{
+	service.ImportNew:       lipgloss.NewStyle().Foreground(lipgloss.Color("10")),
+	service.ImportIdentical: lipgloss.NewStyle().Foreground(lipgloss.Color("8")),
+	service.ImportConflict:  lipgloss.NewStyle().Foreground(lipgloss.Color("3")),
+	service.ImportInvalid:   lipgloss.NewStyle().Foreground(lipgloss.Color("9")),
+}
+
+// printImportPreview shows how every imported account relates to the vault.
+func printImportPreview(plan []service.ImportEntry) {
+	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true).PaddingRight(1)
+	rowStyle := lipgloss.NewStyle().PaddingRight(1)
+
+	tbl := table.New().
+		Border(lipgloss.HiddenBorder()).
+		Headers("STATUS", "ISSUER", "LABEL", "TYPE", "DETAIL").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == table.HeaderRow {
+				return headerStyle
+			}
+			return rowStyle
+		})
+
+	counts := make(map[service.ImportStatus]int)
+	for _, e := range plan {
+		counts[e.Status]++
+		tbl.Row(
+			statusStyles[e.Status].Render(e.Status.String()),
+			e.Account.Issuer,
+			e.Account.DisplayLabel(),
+			strings.ToUpper(string(e.Account.Type)),
+			e.Detail,
+		)
+	}
+
+	fmt.Println(tbl.Render())
+	fmt.Printf("%d new, %d identical, %d conflicting, %d invalid\n",
+		counts[service.ImportNew], counts[service.ImportIdentical],
+		counts[service.ImportConflict], counts[service.ImportInvalid])
+
+	printNormalizationReport(plan)
+}
+
+// printNormalizationReport lists, per entry, what normalization fixed and
+// why entries were rejected.
+func printNormalizationReport(plan []service.ImportEntry) {
+	var lines []string
+	for _, e := range plan {
+		if len(e.Fixes) > 0 {
+			lines = append(lines, fmt.Sprintf("  ✎ %s: %s", e.Account.FullIdentifier(), strings.Join(e.Fixes, ", ")))
+		}
+		if e.Status == service.ImportInvalid {
+			lines = append(lines, statusStyles[service.ImportInvalid].Render(
+				fmt.Sprintf("  ✗ %s: rejected, %s", e.Account.FullIdentifier(), e.Detail)))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	fmt.Println("\nNormalization report:")
+	for _, l := range lines {
+		fmt.Println(l)
+	}
+}
+
+// conflictResolver returns the resolve callback for ApplyImport. The "ask"
+// strategy prompts for each conflict.
+func conflictResolver(strategy string) (service.ResolveFunc, error) {
+	if strategy == "" || strategy == "ask" {
+		return ui.PromptConflict, nil
+	}
+
+	r, err := service.ParseResolution(strategy)
+	if err != nil {
+		return nil, err
+	}
+	return func(service.ImportEntry, string) (service.Resolution, string, error) {
+		return r, "", nil
+	}, nil
+}
+
 var importCmd = &cobra.Command{
 	Use:   "import",
-	Short: "Import accounts from andOTP backups",
+	Short: "Import accounts from a backup, detecting its format automatically",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		strategy, _ := cmd.Flags().GetString("strategy")
+		resolve, err := conflictResolver(strategy)
+		if err != nil {
+			return err
+		}
+
 		filePath, _ := cmd.Flags().GetString("file")
 		if filePath == "" {
 			f, err := ui.PromptInput("Enter backup file path", "Path to the .json backup file")
@@ -29,23 +120,28 @@ var importCmd = &cobra.Command{
 			return err
 		}
 
-		var password string
-		// Check if the backup is encrypted (invalid JSON indicates encryption)
-		var test interface{}
-		if err := json.Unmarshal(data, &test); err != nil {
-			p, err := ui.PromptPassword("Enter Backup Decryption Password")
-			if err != nil {
-				return err
-			}
-			password = p
+		var prov provider.Provider
+		if format, _ := cmd.Flags().GetString("format"); format != "" {
+			prov, err = provider.Get(format)
+		} else {
+			prov, err = provider.Detect(data)
 		}
-
-		prov := andotp.New()
-		accounts, err := prov.Import(filePath, password)
 		if err != nil {
 			return err
 		}
 
+		accounts, err := prov.Import(data, "")
+		if errors.Is(err, provider.ErrPasswordRequired) {
+			password, perr := ui.PromptPassword("Enter Backup Decryption Password")
+			if perr != nil {
+				return perr
+			}
+			accounts, err = prov.Import(data, password)
+		}
+		if err != nil {
+			return fmt.Errorf("%s import failed: %w", prov.Name(), err)
+		}
+
 		store, err := storage.NewStorage()
 		if err != nil {
 			return err
@@ -61,39 +157,61 @@ var importCmd = &cobra.Command{
 			return err
 		}
 
-		// Simple duplicate check
-		seen := make(map[string]bool)
-		for _, e := range existing {
-			seen[e.FullIdentifier()] = true
+		plan := service.PlanImport(existing, accounts)
+		printImportPreview(plan)
+
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			return nil
 		}
 
-		newCount := 0
-		for _, a := range accounts {
-			if !seen[a.FullIdentifier()] {
-				existing = append(existing, a)
-				seen[a.FullIdentifier()] = true
-				newCount++
+		for _, e := range plan {
+			if e.Status == service.ImportNew || e.Status == service.ImportConflict {
+				printWarnings(&e.Account)
 			}
 		}
 
-		if newCount == 0 {
-			fmt.Println("No new accounts found in backup (all already exist).")
+		merged, summary, err := service.ApplyImport(existing, plan, resolve)
+		if err != nil {
+			return err
+		}
+
+		changed := summary.Added + summary.Overwritten + summary.Renamed
+		if changed == 0 {
+			fmt.Println("Nothing to import: every account already exists or is invalid.")
 			return nil
 		}
 
-		if err := store.WriteAccounts(existing, pwd); err != nil {
+		if strategy == "" || strategy == "ask" {
+			ok, err := ui.PromptConfirm(fmt.Sprintf("Apply import (%d added, %d overwritten, %d renamed)?", summary.Added, summary.Overwritten, summary.Renamed))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Import cancelled.")
+				return nil
+			}
+		}
+
+		if err := store.WriteAccounts(merged, pwd); err != nil {
 			return err
 		}
 
-		fmt.Printf("✓ Imported %d new accounts (skipped %d duplicates)\n", newCount, len(accounts)-newCount)
+		fmt.Printf("✓ Imported %d new, overwrote %d, renamed %d (skipped %d, %d invalid)\n",
+			summary.Added, summary.Overwritten, summary.Renamed, summary.Skipped, summary.Invalid)
 		return nil
 	},
 }
 
 var exportCmd = &cobra.Command{
 	Use:   "export",
-	Short: "Export accounts to andOTP format",
+	Short: "Export accounts to a backup format",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		prov, err := provider.Get(format)
+		if err != nil {
+			return err
+		}
+
 		filePath, _ := cmd.Flags().GetString("file")
 		if filePath == "" {
 			filePath = "gauth_backup.json"
@@ -114,12 +232,14 @@ var exportCmd = &cobra.Command{
 			return err
 		}
 
-		password, err := ui.PromptPassword("Enter Password to Encrypt Export (leave empty for plain text)")
-		if err != nil {
-			return err
+		var password string
+		if prov.SupportsEncryption() {
+			password, err = ui.PromptPassword("Enter Password to Encrypt Export (leave empty for plain text)")
+			if err != nil {
+				return err
+			}
 		}
 
-		prov := andotp.New()
 		data, err := prov.Export(accounts, password)
 		if err != nil {
 			return err
@@ -130,13 +250,39 @@ var exportCmd = &cobra.Command{
 		}
 
 		fmt.Printf("✓ Exported %d accounts to %s\n", len(accounts), filePath)
+
+		if qr, ok := prov.(provider.QRCodeExporter); ok {
+			return renderQRCodes(qr.QRPayloads(data))
+		}
 		return nil
 	},
 }
 
+// renderQRCodes prints one scannable QR code per exported payload.
+func renderQRCodes(payloads []string) error {
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true).MarginTop(1)
+	for i, payload := range payloads {
+		code, err := ui.RenderQR(payload)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(titleStyle.Render(fmt.Sprintf("Batch %d of %d", i+1, len(payloads))))
+		fmt.Println(code)
+	}
+
+	fmt.Println("\nScan each code with your authenticator app's import feature.")
+	return nil
+}
+
 func init() {
-	importCmd.Flags().StringP("file", "f", "", "Backup file to import")
-	importCmd.MarkFlagRequired("file")
+	importCmd.Flags().StringP("file", "f", "", "Backup file to import (prompted for when empty)")
+
+	formats := strings.Join(provider.Names(), ", ")
+	importCmd.Flags().String("format", "", "Backup format, detected automatically when empty ("+formats+")")
+	importCmd.Flags().String("strategy", "ask", "How to resolve conflicts: ask, keep, overwrite, rename or both")
+	importCmd.Flags().Bool("dry-run", false, "Only show the import preview")
 
 	exportCmd.Flags().StringP("file", "f", "gauth_backup.json", "Output file")
+	exportCmd.Flags().String("format", "andotp", "Backup format ("+formats+")")
 }
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/leeineian/gauth/internal/storage"
+	"github.com/leeineian/gauth/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var kdfCmd = &cobra.Command{
+	Use:   "kdf",
+	Short: "Inspect and tune the key derivation of the encrypted vault",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := storage.NewStorage()
+		if err != nil {
+			return err
+		}
+
+		pwd, err := getOrPromptPassword(store)
+		if err != nil {
+			return err
+		}
+		if enc, _ := store.IsEncrypted(); !enc {
+			fmt.Println("The database is not encrypted. Run 'gauth -p' to set a master password.")
+			return nil
+		}
+
+		if _, err := store.ReadAccounts(pwd); err != nil {
+			return err
+		}
+
+		params := store.KDFParams()
+		fmt.Printf("%s, unlocks in %s on this machine\n", params, storage.MeasureKDF(params).Round(time.Millisecond))
+		return nil
+	},
+}
+
+var kdfCalibrateCmd = &cobra.Command{
+	Use:   "calibrate",
+	Short: "Benchmark Argon2id and re-encrypt the vault to unlock in a target time",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, _ := cmd.Flags().GetDuration("target")
+		maxMemory, _ := cmd.Flags().GetUint32("max-memory")
+		yes, _ := cmd.Flags().GetBool("yes")
+		if target <= 0 {
+			return fmt.Errorf("--target must be positive")
+		}
+		if maxMemory > storage.MaxKDFMemory/1024 {
+			return fmt.Errorf("--max-memory can't exceed %d MiB", storage.MaxKDFMemory/1024)
+		}
+
+		store, err := storage.NewStorage()
+		if err != nil {
+			return err
+		}
+
+		pwd, err := getOrPromptPassword(store)
+		if err != nil {
+			return err
+		}
+		if enc, _ := store.IsEncrypted(); !enc {
+			return fmt.Errorf("the database is not encrypted, run 'gauth -p' to set a master password first")
+		}
+
+		accounts, err := store.ReadAccounts(pwd)
+		if err != nil {
+			return err
+		}
+		for _, info := range store.Slots() {
+			if info.Unlocked && info.Argon2id == (storage.KDFParams{}) {
+				return fmt.Errorf("the vault was unlocked with a %s, which doesn't use Argon2id; unlock with a password or keyfile to calibrate", info.Type)
+			}
+		}
+
+		current := store.KDFParams()
+		fmt.Printf("Current:  %s, %s\n", current, storage.MeasureKDF(current).Round(time.Millisecond))
+
+		fmt.Printf("Benchmarking for a %s unlock...\n", target)
+		proposed, took := storage.Calibrate(target, maxMemory*1024, storage.MeasureKDF)
+		fmt.Printf("Proposed: %s, %s\n", proposed, took.Round(time.Millisecond))
+
+		if proposed == current {
+			fmt.Println("The vault already uses these parameters.")
+			return nil
+		}
+
+		if !yes {
+			ok, err := ui.PromptConfirm("Re-encrypt the vault with the proposed parameters?")
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Calibration cancelled.")
+				return nil
+			}
+		}
+
+		if err := store.SetKDFParams(proposed); err != nil {
+			return err
+		}
+		if err := store.WriteAccounts(accounts, pwd); err != nil {
+			return err
+		}
+
+		fmt.Println("✓ Vault re-encrypted with the new parameters.")
+		for i, info := range store.Slots() {
+			if info.Argon2id != (storage.KDFParams{}) && info.Argon2id != proposed {
+				fmt.Fprintln(os.Stderr, warningStyle.Render(fmt.Sprintf("! Slot %d (%s) opens with another secret and keeps %s; unlock with it and calibrate again to update it.", i+1, info.Type, info.Argon2id)))
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	kdfCalibrateCmd.Flags().Duration("target", 500*time.Millisecond, "Desired unlock time")
+	kdfCalibrateCmd.Flags().Uint32("max-memory", 1024, "Most memory to use, in MiB")
+	kdfCalibrateCmd.Flags().BoolP("yes", "y", false, "Re-encrypt without asking")
+
+	kdfCmd.AddCommand(kdfCalibrateCmd)
+	rootCmd.AddCommand(kdfCmd)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leeineian/gauth/internal/storage"
+)
+
+func TestKDFCalibrateMaxMemory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer kdfCalibrateCmd.Flags().Set("max-memory", "1024")
+
+	// 4 TiB in MiB would wrap to zero KiB in uint32
+	for _, mib := range []string{"4097", "4194304"} {
+		if err := kdfCalibrateCmd.Flags().Set("max-memory", mib); err != nil {
+			t.Fatal(err)
+		}
+		err := kdfCalibrateCmd.RunE(kdfCalibrateCmd, nil)
+		if err == nil || !strings.Contains(err.Error(), "--max-memory") {
+			t.Errorf("--max-memory %s: got %v", mib, err)
+		}
+	}
+}
+
+// A recovery key opens an HKDF slot, so calibrating would change nothing.
+func TestKDFCalibrateRefusesRecoveryKey(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer func(old string) { masterPassword = old }(masterPassword)
+
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetKDFParams(storage.KDFParams{Time: 1, Memory: 64, Threads: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.WriteAccounts(nil, "password"); err != nil {
+		t.Fatal(err)
+	}
+	recovery, err := store.AddRecoverySlot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.WriteAccounts(nil, "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	masterPassword = recovery
+	err = kdfCalibrateCmd.RunE(kdfCalibrateCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "recovery key") {
+		t.Errorf("got %v", err)
+	}
+}
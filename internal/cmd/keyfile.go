@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/leeineian/gauth/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var keyfileCmd = &cobra.Command{
+	Use:   "keyfile",
+	Short: "Create keyfiles that unlock the vault",
+	Long: `A keyfile is a file whose contents unlock the vault, either alone for
+unattended use or together with the master password. Pass it with
+--keyfile on any command.`,
+}
+
+var keyfileGenerateCmd = &cobra.Command{
+	Use:   "generate <path>",
+	Short: "Write a new random keyfile",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		path := args[0]
+
+		err := storage.GenerateKeyfile(path, force)
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%s already exists, pass --force to replace it", path)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write keyfile: %w", err)
+		}
+
+		fmt.Printf("✓ Keyfile written to %s\n", path)
+		fmt.Fprintln(os.Stderr, warningStyle.Render("! Keep a backup. A vault that needs this keyfile cannot be opened without it."))
+		fmt.Printf("Run 'gauth passwd --keyfile %s' to require it, or 'gauth slots add keyfile %s' to unlock with it alone.\n", path, path)
+		return nil
+	},
+}
+
+func init() {
+	keyfileGenerateCmd.Flags().BoolP("force", "f", false, "Replace an existing file")
+
+	keyfileCmd.AddCommand(keyfileGenerateCmd)
+	rootCmd.AddCommand(keyfileCmd)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/table"
+	"github.com/leeineian/gauth/internal/output"
 	"github.com/leeineian/gauth/internal/storage"
 	"github.com/spf13/cobra"
 )
@@ -14,6 +15,11 @@ var entryListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all accounts",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := parseOutputFlags(cmd)
+		if err != nil {
+			return err
+		}
+
 		store, err := storage.NewStorage()
 		if err != nil {
 			return err
@@ -29,6 +35,14 @@ var entryListCmd = &cobra.Command{
 			return err
 		}
 
+		if opts.enabled() {
+			views := make([]output.Account, 0, len(accounts))
+			for i := range accounts {
+				views = append(views, output.NewAccount(i+1, &accounts[i]))
+			}
+			return writeRecords(cmd.OutOrStdout(), opts, views)
+		}
+
 		if len(accounts) == 0 {
 			fmt.Println("No accounts found.")
 			return nil
@@ -62,3 +76,7 @@ var entryListCmd = &cobra.Command{
 		return nil
 	},
 }
+
+func init() {
+	addOutputFlags(entryListCmd, "accounts (never includes secrets)")
+}
@@ -0,0 +1,10 @@
+package cmd
+
+// Backup formats register themselves with the provider registry on import.
+import (
+	_ "github.com/leeineian/gauth/internal/provider/aegis"
+	_ "github.com/leeineian/gauth/internal/provider/andotp"
+	_ "github.com/leeineian/gauth/internal/provider/gmigration"
+	_ "github.com/leeineian/gauth/internal/provider/otpauth"
+	_ "github.com/leeineian/gauth/internal/provider/twofas"
+)
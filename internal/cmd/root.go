@@ -6,10 +6,13 @@ import (
 	"runtime"
 	"runtime/debug"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/table"
+	"github.com/leeineian/gauth/internal/clipboard"
 	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/output"
 	"github.com/leeineian/gauth/internal/service"
 	"github.com/leeineian/gauth/internal/storage"
 	"github.com/leeineian/gauth/internal/ui"
@@ -40,10 +43,40 @@ var (
 		SilenceErrors: true,
 	}
 
-	watchFlag bool
+	watchFlag  bool
+	clearAfter time.Duration
 )
 
+// shortcuts maps the short root flags to the subcommands they stand for.
+var shortcuts = []struct {
+	name, short, usage string
+	cmd                *cobra.Command
+}{
+	{"passwd", "p", "set or change the master password", passwdCmd},
+	{"export", "e", "export accounts to a backup format", exportCmd},
+	{"import", "i", "import accounts from a backup", importCmd},
+	{"list", "l", "list all accounts", entryListCmd},
+	{"add", "a", "add a new account", entryAddCmd},
+	{"delete", "d", "delete an account", entryDeleteCmd},
+	{"next", "n", "advance an HOTP account to its next code", entryNextCmd},
+}
+
+// expandShortcut rewrites a leading shortcut flag into its subcommand, so
+// "gauth -i -f backup.json" parses exactly like "gauth import -f backup.json".
+func expandShortcut(args []string) []string {
+	if len(args) == 0 {
+		return args
+	}
+	for _, s := range shortcuts {
+		if args[0] == "-"+s.short || args[0] == "--"+s.name {
+			return append([]string{s.cmd.Name()}, args[1:]...)
+		}
+	}
+	return args
+}
+
 func Execute() {
+	rootCmd.SetArgs(expandShortcut(os.Args[1:]))
 	if err := rootCmd.Execute(); err != nil {
 		errorStyle := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("9")).
@@ -57,46 +90,47 @@ func Execute() {
 
 func init() {
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.PersistentFlags().StringVar(&keyfilePath, "keyfile", "", "unlock with this keyfile, alone or together with the master password")
 
 	rootCmd.Flags().BoolVarP(&watchFlag, "watch", "w", false, "watch codes update in real-time")
+	addOutputFlags(rootCmd, "codes")
+	rootCmd.Flags().DurationVar(&clearAfter, "clear-after", clipboard.DefaultClearAfter, "wipe codes copied in watch mode after this long or when they expire (0 keeps them); without a readable system clipboard the OSC 52 copy is wiped even if something else was copied since")
 
-	var versionFlag, passwdFlag, exportFlag, importFlag, accountFlag, addFlag, deleteFlag bool
+	var versionFlag bool
 	rootCmd.Flags().BoolVarP(&versionFlag, "version", "v", false, "print version information")
-	rootCmd.Flags().BoolVarP(&passwdFlag, "passwd", "p", false, "set or change the master password")
-	rootCmd.Flags().BoolVarP(&exportFlag, "export", "e", false, "export accounts to andOTP format")
-	rootCmd.Flags().BoolVarP(&importFlag, "import", "i", false, "import accounts from andOTP backups")
-	rootCmd.Flags().BoolVarP(&accountFlag, "list", "l", false, "list all accounts")
-	rootCmd.Flags().BoolVarP(&addFlag, "add", "a", false, "add a new account")
-	rootCmd.Flags().BoolVarP(&deleteFlag, "delete", "d", false, "delete an account")
 
+	selected := make([]bool, len(shortcuts))
+	for i, s := range shortcuts {
+		rootCmd.Flags().BoolVarP(&selected[i], s.name, s.short, false, s.usage+" (same as 'gauth "+s.cmd.Name()+"')")
+		rootCmd.AddCommand(s.cmd)
+	}
+
+	// Shortcuts are expanded only as the first argument. Anywhere else, e.g.
+	// "gauth -o json -l", the flags around them would belong to the wrong
+	// command, so they are refused.
 	rootCmd.PreRunE = func(cmd *cobra.Command, args []string) error {
 		if versionFlag {
 			fmt.Println(getVersion())
 			os.Exit(0)
 		}
-		if passwdFlag {
-			return passwdCmd.RunE(passwdCmd, args)
-		}
-		if exportFlag {
-			return exportCmd.RunE(exportCmd, args)
-		}
-		if importFlag {
-			return importCmd.RunE(importCmd, args)
-		}
-		if accountFlag {
-			return entryListCmd.RunE(entryListCmd, args)
-		}
-		if addFlag {
-			return entryAddCmd.RunE(entryAddCmd, args)
-		}
-		if deleteFlag {
-			return entryDeleteCmd.RunE(entryDeleteCmd, args)
+		for i, s := range shortcuts {
+			if selected[i] {
+				return fmt.Errorf("-%s must be the first argument, or use 'gauth %s'", s.short, s.cmd.Name())
+			}
 		}
 		return nil
 	}
 }
 
 func runRoot(cmd *cobra.Command, args []string) error {
+	opts, err := parseOutputFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if opts.enabled() && watchFlag {
+		return fmt.Errorf("--output and --format cannot be combined with --watch")
+	}
+
 	store, err := storage.NewStorage()
 	if err != nil {
 		return err
@@ -112,6 +146,10 @@ func runRoot(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if opts.enabled() {
+		return writeCodes(cmd, opts, accounts)
+	}
+
 	if len(accounts) == 0 {
 		fmt.Println("No accounts found. Use 'gauth -a' to add one.")
 		return nil
@@ -120,11 +158,13 @@ func runRoot(cmd *cobra.Command, args []string) error {
 	// Proactively suggest encryption if it's currently plain text
 	isEnc, _ := store.IsEncrypted()
 	if !isEnc {
-		fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Render("! Your database is currently unencrypted. Run 'gauth -p' to set a master password."))
+		fmt.Fprintln(os.Stderr, warningStyle.Render("! Your database is currently unencrypted. Run 'gauth -p' to set a master password."))
 	}
 
 	if watchFlag {
-		return ui.RunLiveView(accounts)
+		return ui.RunLiveView(accounts, func(updated []model.Account) error {
+			return store.WriteAccounts(updated, pwd)
+		}, clearAfter)
 	}
 
 	otpSvc := service.NewOTPService()
@@ -149,11 +189,11 @@ func runRoot(cmd *cobra.Command, args []string) error {
 
 		if err == nil {
 			codeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Bold(true)
-			if res.Remaining < 5 && acc.Type == model.TypeTOTP {
+			if res.Remaining < 5 && acc.Type.IsTimeBased() {
 				codeStyle = codeStyle.Foreground(lipgloss.Color("9"))
 			}
 			code = codeStyle.Render(res.Code)
-			if acc.Type == model.TypeTOTP {
+			if acc.Type.IsTimeBased() {
 				remaining = fmt.Sprintf("%ds", res.Remaining)
 			}
 		}
@@ -170,3 +210,20 @@ func runRoot(cmd *cobra.Command, args []string) error {
 	fmt.Println(tbl.Render())
 	return nil
 }
+
+// writeCodes prints the current codes in a machine-readable format. Accounts
+// whose code cannot be generated are reported on stderr and left out.
+func writeCodes(cmd *cobra.Command, opts outputOptions, accounts []model.Account) error {
+	otpSvc := service.NewOTPService()
+
+	var codes []output.Code
+	for i := range accounts {
+		res, err := otpSvc.Generate(&accounts[i])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, warningStyle.Render(fmt.Sprintf("! %s: %v", accounts[i].FullIdentifier(), err)))
+			continue
+		}
+		codes = append(codes, output.NewCode(&accounts[i], res))
+	}
+	return writeRecords(cmd.OutOrStdout(), opts, codes)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestExpandShortcut(t *testing.T) {
+	tests := []struct {
+		args, want []string
+	}{
+		{nil, nil},
+		{[]string{"-i", "-f", "backup.json"}, []string{"import", "-f", "backup.json"}},
+		{[]string{"--list", "-o", "json"}, []string{"list", "-o", "json"}},
+		{[]string{"-o", "json", "-l"}, []string{"-o", "json", "-l"}},
+	}
+	for _, tt := range tests {
+		if got := expandShortcut(tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandShortcut(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+// A shortcut after other flags must not run its subcommand and the codes.
+func TestShortcutNotFirst(t *testing.T) {
+	reset := func() {
+		rootCmd.Flags().VisitAll(func(f *pflag.Flag) {
+			f.Value.Set(f.DefValue)
+			f.Changed = false
+		})
+	}
+	t.Cleanup(reset)
+
+	for _, args := range [][]string{{"-o", "json", "-l"}, {"-w", "-a"}} {
+		if err := rootCmd.ParseFlags(expandShortcut(args)); err != nil {
+			t.Fatal(err)
+		}
+		err := rootCmd.PreRunE(rootCmd, nil)
+		if err == nil || !strings.Contains(err.Error(), "first argument") {
+			t.Errorf("%q: got %v", args, err)
+		}
+		reset()
+	}
+}
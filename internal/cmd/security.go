@@ -8,13 +8,15 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var masterPassword string
+var (
+	masterPassword string
+	keyfilePath    string
+)
 
+// getOrPromptPassword returns the master password of an encrypted vault,
+// prompting at most once per run. With --keyfile the keyfile is loaded into
+// store first, and vaults it unlocks on its own don't prompt at all.
 func getOrPromptPassword(store *storage.Storage) (string, error) {
-	if masterPassword != "" {
-		return masterPassword, nil
-	}
-
 	isEnc, err := store.IsEncrypted()
 	if err != nil {
 		return "", err
@@ -24,6 +26,24 @@ func getOrPromptPassword(store *storage.Storage) (string, error) {
 		return "", nil // Plain text
 	}
 
+	if keyfilePath != "" {
+		if err := store.UseKeyfile(keyfilePath); err != nil {
+			return "", err
+		}
+	}
+	if masterPassword != "" {
+		return masterPassword, nil
+	}
+	if keyfilePath != "" {
+		need, err := store.NeedsPassword()
+		if err != nil {
+			return "", err
+		}
+		if !need {
+			return "", nil
+		}
+	}
+
 	pwd, err := ui.PromptPassword("Enter Master Password")
 	if err != nil {
 		return "", err
@@ -35,12 +55,28 @@ func getOrPromptPassword(store *storage.Storage) (string, error) {
 var passwdCmd = &cobra.Command{
 	Use:   "passwd",
 	Short: "Set or change the master password",
+	Long: `Set or change the master password.
+
+The new password replaces every password and keyfile key slot; recovery
+keys keep working. Leaving it empty removes encryption, and with it all
+key slots.
+
+With --keyfile, the new password and the keyfile are both needed to
+unlock the vault, and leaving the password empty lets the keyfile unlock
+it alone.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		store, err := storage.NewStorage()
 		if err != nil {
 			return err
 		}
 
+		var cipherID uint8
+		if name, _ := cmd.Flags().GetString("cipher"); name != "" {
+			if cipherID, err = storage.ParseCipher(name); err != nil {
+				return err
+			}
+		}
+
 		currentPwd, err := getOrPromptPassword(store)
 		if err != nil {
 			return err
@@ -51,21 +87,48 @@ var passwdCmd = &cobra.Command{
 			return err
 		}
 
-		newPwd, err := ui.PromptNewPassword()
+		prompt := ui.PromptNewPassword
+		if keyfilePath != "" {
+			// Plain vaults haven't loaded the keyfile yet
+			if err := store.UseKeyfile(keyfilePath); err != nil {
+				return err
+			}
+			prompt = ui.PromptKeyfilePassword
+		}
+
+		newPwd, err := prompt()
 		if err != nil {
 			return err
 		}
 
+		if cipherID != 0 {
+			if newPwd == "" && keyfilePath == "" {
+				return fmt.Errorf("--cipher needs a password, the database would be unencrypted")
+			}
+			if err := store.SetCipher(cipherID); err != nil {
+				return err
+			}
+		}
+
 		if err := store.WriteAccounts(accounts, newPwd); err != nil {
 			return err
 		}
 
 		masterPassword = newPwd
-		if newPwd == "" {
+		switch {
+		case newPwd == "" && keyfilePath == "":
 			fmt.Println("✓ Master password removed. Database is now unencrypted.")
-		} else {
-			fmt.Println("✓ Master password updated successfully!")
+		case newPwd == "":
+			fmt.Printf("✓ The keyfile alone now unlocks the database. (%s)\n", storage.CipherName(store.Cipher()))
+		case keyfilePath != "":
+			fmt.Printf("✓ Master password and keyfile set! Both are needed to unlock. (%s)\n", storage.CipherName(store.Cipher()))
+		default:
+			fmt.Printf("✓ Master password updated successfully! (%s)\n", storage.CipherName(store.Cipher()))
 		}
 		return nil
 	},
 }
+
+func init() {
+	passwdCmd.Flags().String("cipher", "", "Encrypt with aes-256-gcm (default) or xchacha20-poly1305; kept from the current vault when empty")
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/storage"
+	"github.com/leeineian/gauth/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// openEncrypted reads the vault and fails unless it is encrypted, since only
+// encrypted vaults have key slots.
+func openEncrypted() (*storage.Storage, []model.Account, string, error) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	pwd, err := getOrPromptPassword(store)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if enc, _ := store.IsEncrypted(); !enc {
+		return nil, nil, "", fmt.Errorf("the database is not encrypted, run 'gauth -p' to set a master password first")
+	}
+
+	accounts, err := store.ReadAccounts(pwd)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return store, accounts, pwd, nil
+}
+
+var slotsCmd = &cobra.Command{
+	Use:   "slots",
+	Short: "Manage the passwords, keyfiles and recovery keys that unlock the vault",
+	Long: `The vault is encrypted with a random data key. Each key slot stores a copy
+of that key, encrypted with one password, keyfile, password and keyfile
+pair, or recovery key, so any of them unlocks the vault and adding or
+removing one leaves the others untouched.`,
+}
+
+var slotsListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the key slots",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, _, _, err := openEncrypted()
+		if err != nil {
+			return err
+		}
+
+		slots := store.Slots()
+		if len(slots) == 0 {
+			fmt.Println("This vault predates key slots; the next save adds one for your master password.")
+			return nil
+		}
+
+		headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true).PaddingRight(1)
+		rowStyle := lipgloss.NewStyle().PaddingRight(1)
+
+		tbl := table.New().
+			Border(lipgloss.HiddenBorder()).
+			Headers("SLOT", "TYPE", "PARAMETERS", "").
+			StyleFunc(func(row, col int) lipgloss.Style {
+				if row == table.HeaderRow {
+					return headerStyle
+				}
+				return rowStyle
+			})
+
+		for i, slot := range slots {
+			var used string
+			if slot.Unlocked {
+				used = "← unlocked with"
+			}
+			tbl.Row(strconv.Itoa(i+1), slot.Type.String(), slot.KDF, used)
+		}
+
+		fmt.Println(tbl.Render())
+		return nil
+	},
+}
+
+var slotsAddCmd = &cobra.Command{
+	Use:   "add password|recovery|keyfile|composite [keyfile]",
+	Short: "Add a password or keyfile, or generate a recovery key",
+	Long: `Add a way to unlock the vault:
+
+  password            another master password
+  recovery            a generated recovery key, shown once
+  keyfile <path>      the keyfile alone, for unattended use
+  composite <path>    a new password together with the keyfile`,
+	Args:      cobra.RangeArgs(1, 2),
+	ValidArgs: []string{"password", "recovery", "keyfile", "composite"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "password", "recovery":
+			if len(args) > 1 {
+				return fmt.Errorf("%s slots take no keyfile", args[0])
+			}
+		case "keyfile", "composite":
+			if len(args) < 2 {
+				return fmt.Errorf("%s slots need the path of a keyfile, see 'gauth keyfile generate'", args[0])
+			}
+		default:
+			return fmt.Errorf("unknown slot type %q (use password, recovery, keyfile or composite)", args[0])
+		}
+
+		store, accounts, pwd, err := openEncrypted()
+		if err != nil {
+			return err
+		}
+
+		var recoveryKey string
+		switch args[0] {
+		case "password":
+			newPwd, err := ui.PromptSlotPassword()
+			if err != nil {
+				return err
+			}
+			if err := store.AddPasswordSlot(newPwd); err != nil {
+				return err
+			}
+		case "recovery":
+			if recoveryKey, err = store.AddRecoverySlot(); err != nil {
+				return err
+			}
+		case "keyfile":
+			if err := store.AddKeyfileSlot(args[1], ""); err != nil {
+				return err
+			}
+		case "composite":
+			newPwd, err := ui.PromptCompositePassword()
+			if err != nil {
+				return err
+			}
+			if err := store.AddKeyfileSlot(args[1], newPwd); err != nil {
+				return err
+			}
+		}
+
+		if err := store.WriteAccounts(accounts, pwd); err != nil {
+			return err
+		}
+
+		switch args[0] {
+		case "password":
+			fmt.Println("✓ Password added. Either password now unlocks the vault.")
+			return nil
+		case "keyfile":
+			fmt.Printf("✓ Keyfile added. Run 'gauth --keyfile %s' to unlock without a password.\n", args[1])
+			return nil
+		case "composite":
+			fmt.Println("✓ Password and keyfile added. Together they now unlock the vault.")
+			return nil
+		}
+
+		keyStyle := lipgloss.NewStyle().Bold(true).Border(lipgloss.RoundedBorder()).Padding(0, 2)
+		fmt.Println(keyStyle.Render(recoveryKey))
+		fmt.Fprintln(os.Stderr, warningStyle.Render("! Write this recovery key down and keep it safe. It is shown only once."))
+		fmt.Println("Enter it at the password prompt to unlock the vault, then run 'gauth -p' to set a new password.")
+		return nil
+	},
+}
+
+var slotsRemoveCmd = &cobra.Command{
+	Use:     "remove <slot>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a key slot",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("slot must be a number as shown by 'gauth slots list'")
+		}
+
+		store, accounts, pwd, err := openEncrypted()
+		if err != nil {
+			return err
+		}
+
+		slots := store.Slots()
+		if n < 1 || n > len(slots) {
+			return fmt.Errorf("no key slot %d", n)
+		}
+
+		title := fmt.Sprintf("Remove slot %d (%s)?", n, slots[n-1].Type)
+		if slots[n-1].Unlocked {
+			title = fmt.Sprintf("Remove slot %d (%s)? It is the one you just unlocked with.", n, slots[n-1].Type)
+		}
+		if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+			ok, err := ui.PromptConfirm(title)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Println("Removal cancelled.")
+				return nil
+			}
+		}
+
+		if err := store.RemoveSlot(n - 1); err != nil {
+			return err
+		}
+		if err := store.WriteAccounts(accounts, pwd); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Removed key slot %d\n", n)
+		return nil
+	},
+}
+
+func init() {
+	slotsRemoveCmd.Flags().BoolP("yes", "y", false, "Remove without asking")
+
+	slotsCmd.AddCommand(slotsListCmd, slotsAddCmd, slotsRemoveCmd)
+	rootCmd.AddCommand(slotsCmd)
+}
@@ -8,10 +8,32 @@ import (
 type OTPType string
 
 const (
-	TypeTOTP OTPType = "totp"
-	TypeHOTP OTPType = "hotp"
+	TypeTOTP  OTPType = "totp"
+	TypeHOTP  OTPType = "hotp"
+	TypeSteam OTPType = "steam"
 )
 
+// IsTimeBased reports whether codes for this type rotate with the clock.
+func (t OTPType) IsTimeBased() bool {
+	return t.is(TypeTOTP) || t.is(TypeSteam)
+}
+
+// IsHOTP reports whether codes for this type advance with a counter.
+func (t OTPType) IsHOTP() bool {
+	return t.is(TypeHOTP)
+}
+
+// IsSteam reports whether codes for this type use Steam's alphabet.
+func (t OTPType) IsSteam() bool {
+	return t.is(TypeSteam)
+}
+
+// is compares types ignoring case, since older vaults and imports may store
+// them upper-case.
+func (t OTPType) is(other OTPType) bool {
+	return strings.EqualFold(string(t), string(other))
+}
+
 type Account struct {
 	Secret    string                 `json:"secret"`
 	Label     string                 `json:"label"`
@@ -24,14 +46,20 @@ type Account struct {
 	Misc      map[string]interface{} `json:"misc,omitempty"`
 }
 
+// DisplayLabel returns the label without a redundant "Issuer:" prefix. Only
+// a prefix matching the issuer is stripped, so labels that legitimately
+// contain colons are kept intact.
 func (a *Account) DisplayLabel() string {
-	if a.Label != "" && strings.Contains(a.Label, ":") {
-		return strings.Split(a.Label, ":")[1]
+	if a.Label == "" {
+		return "unnamed"
 	}
-	if a.Label != "" {
-		return a.Label
+	if prefix, rest, ok := strings.Cut(a.Label, ":"); ok && a.Issuer != "" &&
+		strings.EqualFold(strings.TrimSpace(prefix), strings.TrimSpace(a.Issuer)) {
+		if rest = strings.TrimSpace(rest); rest != "" {
+			return rest
+		}
 	}
-	return "unnamed"
+	return a.Label
 }
 
 func (a *Account) FullIdentifier() string {
@@ -41,31 +69,81 @@ func (a *Account) FullIdentifier() string {
 	return a.DisplayLabel()
 }
 
+// OTPResult is a generated code. PeriodStart and PeriodEnd are the Unix
+// times bounding a time-based code's validity; both are zero for HOTP.
 type OTPResult struct {
-	Code      string
-	Remaining int64
+	Code        string
+	Remaining   int64
+	PeriodStart int64
+	PeriodEnd   int64
 }
 
 func (a *Account) Validate() error {
 	if a.Secret == "" {
 		return fmt.Errorf("secret is required")
 	}
+	if !validSecret(a.Secret) {
+		return fmt.Errorf("secret is not valid Base32")
+	}
 	if a.Label == "" {
 		return fmt.Errorf("label is required")
 	}
 	if a.Issuer == "" {
 		return fmt.Errorf("issuer is required")
 	}
-	if a.Digits != 6 && a.Digits != 8 {
-		return fmt.Errorf("digits must be 6 or 8")
+	switch a.Type {
+	case TypeTOTP, TypeHOTP, TypeSteam:
+	default:
+		return fmt.Errorf("unsupported type: %q", a.Type)
+	}
+	switch a.Algorithm {
+	case "sha1", "sha256", "sha512":
+	default:
+		return fmt.Errorf("unsupported algorithm: %q", a.Algorithm)
+	}
+	if a.Type == TypeSteam {
+		if a.Digits != SteamDigits {
+			return fmt.Errorf("steam codes must have %d characters", SteamDigits)
+		}
+		if a.Period != 0 && a.Period != SteamPeriod {
+			return fmt.Errorf("steam codes must use a %ds period", SteamPeriod)
+		}
+		return nil
+	}
+	if a.Digits < MinDigits || a.Digits > MaxDigits {
+		return fmt.Errorf("digits must be between %d and %d", MinDigits, MaxDigits)
+	}
+	if a.Period < 0 {
+		return fmt.Errorf("period must not be negative")
+	}
+	if a.Counter < 0 {
+		return fmt.Errorf("counter must not be negative")
 	}
 	return nil
 }
 
+// Warnings lists parameters that are valid but unusual enough that they are
+// probably a typo, so callers can surface them without refusing the account.
+func (a *Account) Warnings() []string {
+	var warnings []string
+	if a.Type != TypeSteam && a.Digits != 6 && a.Digits != 8 {
+		warnings = append(warnings, fmt.Sprintf("%d digits is unusual; most services use 6 or 8", a.Digits))
+	}
+	if a.Type.IsTimeBased() && a.Period != 0 && a.Period != 30 && a.Period != 60 {
+		warnings = append(warnings, fmt.Sprintf("a %ds period is unusual; most services use 30 or 60", a.Period))
+	}
+	return warnings
+}
+
 const (
 	DefaultDigits  = 6
 	DefaultType    = TypeTOTP
 	DefaultCounter = 0
 	DefaultAlgo    = "sha1"
 	DefaultPeriod  = 30
+
+	SteamDigits = 5
+	SteamPeriod = 30 // Steam only accepts codes on a 30s step
+	MinDigits   = 4
+	MaxDigits   = 10
 )
@@ -0,0 +1,26 @@
+package model
+
+import "testing"
+
+func TestOTPTypeIgnoresCase(t *testing.T) {
+	tests := []struct {
+		typ       OTPType
+		hotp      bool
+		timeBased bool
+	}{
+		{"hotp", true, false},
+		{"HOTP", true, false},
+		{"totp", false, true},
+		{"TOTP", false, true},
+		{"Steam", false, true},
+		{"motp", false, false},
+	}
+	for _, tt := range tests {
+		if got := tt.typ.IsHOTP(); got != tt.hotp {
+			t.Errorf("%q.IsHOTP() = %v, want %v", tt.typ, got, tt.hotp)
+		}
+		if got := tt.typ.IsTimeBased(); got != tt.timeBased {
+			t.Errorf("%q.IsTimeBased() = %v, want %v", tt.typ, got, tt.timeBased)
+		}
+	}
+}
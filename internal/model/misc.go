@@ -0,0 +1,57 @@
+package model
+
+// Misc values lose their Go types once the vault has been written and read
+// back as JSON: numbers come back as float64 and lists as []interface{}.
+// These helpers accept both the in-memory and the reloaded forms.
+
+// MiscString returns the string stored under key, or "".
+func (a *Account) MiscString(key string) string {
+	if v, ok := a.Misc[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// MiscInt returns the number stored under key.
+func (a *Account) MiscInt(key string) (int64, bool) {
+	switch v := a.Misc[key].(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// Tags returns the account's tags (andOTP) and groups (Aegis, 2FAS) without
+// duplicates.
+func (a *Account) Tags() []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, t := range append(a.MiscStrings("tags"), a.MiscStrings("groups")...) {
+		if !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// MiscStrings returns the string list stored under key.
+func (a *Account) MiscStrings(key string) []string {
+	switch v := a.Misc[key].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
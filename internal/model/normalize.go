@@ -0,0 +1,90 @@
+package model
+
+import (
+	"encoding/base32"
+	"fmt"
+	"strings"
+)
+
+var secretCleaner = strings.NewReplacer(" ", "", "-", "", "\t", "", "\n", "", "\r", "")
+
+// Normalize repairs the common defects of accounts coming from other apps
+// and returns a description of every fix it made. It never rejects an
+// account; call Validate afterwards for that.
+func (a *Account) Normalize() []string {
+	var fixes []string
+
+	if secret := strings.TrimRight(strings.ToUpper(secretCleaner.Replace(a.Secret)), "="); secret != a.Secret {
+		a.Secret = secret
+		fixes = append(fixes, "normalized secret")
+	}
+
+	if issuer := strings.TrimSpace(a.Issuer); issuer != a.Issuer {
+		a.Issuer = issuer
+		fixes = append(fixes, "trimmed issuer")
+	}
+	if label := strings.TrimSpace(a.Label); label != a.Label {
+		a.Label = label
+		fixes = append(fixes, "trimmed label")
+	}
+
+	// Split "Issuer:label" labels, taking the issuer from the prefix when
+	// it is missing and dropping the prefix when it repeats the issuer.
+	if prefix, rest, ok := strings.Cut(a.Label, ":"); ok {
+		prefix, rest = strings.TrimSpace(prefix), strings.TrimSpace(rest)
+		if a.Issuer == "" && prefix != "" && rest != "" {
+			a.Issuer = prefix
+			fixes = append(fixes, fmt.Sprintf("took issuer %q from label", prefix))
+		}
+		if rest != "" && strings.EqualFold(prefix, a.Issuer) {
+			a.Label = rest
+			fixes = append(fixes, "removed issuer from label")
+		}
+	}
+
+	if typ := OTPType(strings.ToLower(strings.TrimSpace(string(a.Type)))); typ != a.Type {
+		a.Type = typ
+		if typ != "" {
+			fixes = append(fixes, "normalized type")
+		}
+	}
+	if a.Type == "" {
+		a.Type = DefaultType
+		fixes = append(fixes, fmt.Sprintf("defaulted type to %s", DefaultType))
+	}
+
+	algo := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(a.Algorithm), "-", ""))
+	if algo == "" {
+		algo = DefaultAlgo
+		fixes = append(fixes, fmt.Sprintf("defaulted algorithm to %s", DefaultAlgo))
+	} else if algo != a.Algorithm {
+		fixes = append(fixes, "normalized algorithm")
+	}
+	a.Algorithm = algo
+
+	switch {
+	case a.Type == TypeSteam && a.Digits != SteamDigits:
+		a.Digits = SteamDigits
+		fixes = append(fixes, fmt.Sprintf("set digits to %d for Steam", SteamDigits))
+	case a.Digits == 0:
+		a.Digits = DefaultDigits
+		fixes = append(fixes, fmt.Sprintf("defaulted digits to %d", DefaultDigits))
+	}
+
+	if a.Type == TypeSteam && a.Period != 0 && a.Period != SteamPeriod {
+		a.Period = SteamPeriod
+		fixes = append(fixes, fmt.Sprintf("set period to %ds for Steam", SteamPeriod))
+	}
+	if a.Type.IsTimeBased() && a.Period == 0 {
+		a.Period = DefaultPeriod
+		fixes = append(fixes, fmt.Sprintf("defaulted period to %ds", DefaultPeriod))
+	}
+
+	return fixes
+}
+
+// validSecret reports whether s decodes as unpadded Base32.
+func validSecret(s string) bool {
+	_, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(s, "="))
+	return err == nil
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    Account
+		want  Account
+		fixes int
+	}{
+		{
+			name:  "already clean",
+			in:    Account{Secret: "JBSWY3DPEHPK3PXP", Issuer: "GitHub", Label: "me", Type: TypeTOTP, Digits: 6, Algorithm: "sha1", Period: 30},
+			want:  Account{Secret: "JBSWY3DPEHPK3PXP", Issuer: "GitHub", Label: "me", Type: TypeTOTP, Digits: 6, Algorithm: "sha1", Period: 30},
+			fixes: 0,
+		},
+		{
+			name:  "messy secret and missing defaults",
+			in:    Account{Secret: "jbsw y3dp-ehpk 3pxp==", Issuer: "GitHub", Label: "me"},
+			want:  Account{Secret: "JBSWY3DPEHPK3PXP", Issuer: "GitHub", Label: "me", Type: TypeTOTP, Digits: 6, Algorithm: "sha1", Period: 30},
+			fixes: 5,
+		},
+		{
+			name:  "issuer taken from label",
+			in:    Account{Secret: "JBSWY3DPEHPK3PXP", Label: "ACME Co: john:doe", Type: "HOTP", Digits: 8, Algorithm: "SHA-256"},
+			want:  Account{Secret: "JBSWY3DPEHPK3PXP", Issuer: "ACME Co", Label: "john:doe", Type: TypeHOTP, Digits: 8, Algorithm: "sha256"},
+			fixes: 4,
+		},
+		{
+			name:  "steam digits",
+			in:    Account{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Steam", Label: "Steam:gamer", Type: "STEAM", Digits: 6, Algorithm: "sha1", Period: 30},
+			want:  Account{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Steam", Label: "gamer", Type: TypeSteam, Digits: 5, Algorithm: "sha1", Period: 30},
+			fixes: 3,
+		},
+		{
+			name:  "steam period",
+			in:    Account{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Steam", Label: "gamer", Type: TypeSteam, Digits: 5, Algorithm: "sha1", Period: 60},
+			want:  Account{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Steam", Label: "gamer", Type: TypeSteam, Digits: 5, Algorithm: "sha1", Period: 30},
+			fixes: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		acc := tt.in
+		fixes := acc.Normalize()
+		if !reflect.DeepEqual(acc, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, acc, tt.want)
+		}
+		if len(fixes) != tt.fixes {
+			t.Errorf("%s: expected %d fixes, got %v", tt.name, tt.fixes, fixes)
+		}
+		if err := acc.Validate(); err != nil {
+			t.Errorf("%s: Validate() error = %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	for _, acc := range []Account{
+		{Secret: "NOT-BASE32!", Issuer: "X", Label: "y", Type: TypeTOTP, Digits: 6, Algorithm: "sha1"},
+		{Secret: "JBSWY3DPEHPK3PXP", Label: "y", Type: TypeTOTP, Digits: 6, Algorithm: "sha1"},
+		{Secret: "JBSWY3DPEHPK3PXP", Issuer: "X", Label: "y", Type: "motp", Digits: 6, Algorithm: "sha1"},
+		{Secret: "JBSWY3DPEHPK3PXP", Issuer: "X", Label: "y", Type: TypeTOTP, Digits: 6, Algorithm: "md5"},
+		{Secret: "JBSWY3DPEHPK3PXP", Issuer: "X", Label: "y", Type: TypeTOTP, Digits: 11, Algorithm: "sha1"},
+	} {
+		if err := acc.Validate(); err == nil {
+			t.Errorf("Validate(%+v) expected error, got nil", acc)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Package output renders codes and accounts in machine-readable formats.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Format is an output format selected with -o.
+type Format string
+
+const (
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+	CSV   Format = "csv"
+	TSV   Format = "tsv"
+	Plain Format = "plain"
+)
+
+// Formats lists the supported formats for help texts.
+var Formats = []Format{JSON, YAML, CSV, TSV, Plain}
+
+// ParseFormat validates a format name.
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if Format(strings.ToLower(name)) == f {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q (use json, yaml, csv, tsv or plain)", name)
+}
+
+// Field is a named value of a record, in output order.
+type Field struct {
+	Name  string
+	Value interface{}
+}
+
+// Record is a view that can be written in every format. Its JSON encoding
+// must use the same names as its fields.
+type Record interface {
+	Fields() []Field
+}
+
+// Write renders records to w in format f.
+func Write[T Record](w io.Writer, f Format, records []T) error {
+	switch f {
+	case JSON:
+		if records == nil {
+			records = []T{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case YAML:
+		return writeYAML(w, records)
+	case CSV:
+		return writeDelimited(w, ',', records)
+	case TSV:
+		return writeDelimited(w, '\t', records)
+	case Plain:
+		return writePlain(w, records)
+	}
+	return fmt.Errorf("unknown output format %q", f)
+}
+
+func writeYAML[T Record](w io.Writer, records []T) error {
+	if len(records) == 0 {
+		_, err := fmt.Fprintln(w, "[]")
+		return err
+	}
+
+	var b strings.Builder
+	for _, r := range records {
+		for i, field := range r.Fields() {
+			prefix := "  "
+			if i == 0 {
+				prefix = "- "
+			}
+			fmt.Fprintf(&b, "%s%s: %s\n", prefix, field.Name, yamlValue(field.Value))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// yamlValue renders scalars in flow style. Strings are double-quoted with
+// JSON escaping, which YAML accepts, so no value can change type or break
+// the document.
+func yamlValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		q, _ := json.Marshal(v)
+		return string(q)
+	case []string:
+		items := make([]string, len(v))
+		for i, s := range v {
+			items[i] = yamlValue(s)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return cell(v)
+}
+
+func writeDelimited[T Record](w io.Writer, comma rune, records []T) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	var header []string
+	for _, f := range fieldsOf(records) {
+		header = append(header, f.Name)
+	}
+	if header != nil {
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range records {
+		var row []string
+		for _, f := range r.Fields() {
+			row = append(row, cell(f.Value))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writePlain prints an aligned table without colors or borders.
+func writePlain[T Record](w io.Writer, records []T) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	var header []string
+	for _, f := range fieldsOf(records) {
+		header = append(header, strings.ToUpper(f.Name))
+	}
+	if header != nil {
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+	}
+
+	for _, r := range records {
+		var row []string
+		for _, f := range r.Fields() {
+			row = append(row, cell(f.Value))
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func fieldsOf[T Record](records []T) []Field {
+	if len(records) == 0 {
+		return nil
+	}
+	return records[0].Fields()
+}
+
+// cell renders a value for the tabular formats. Lists are comma-joined.
+func cell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case []string:
+		return strings.Join(v, ",")
+	}
+	return fmt.Sprint(v)
+}
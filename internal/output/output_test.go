@@ -0,0 +1,161 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/leeineian/gauth/internal/model"
+)
+
+func testViews() ([]Code, []Account) {
+	totp := model.Account{
+		Issuer: "GitHub", Label: "GitHub:me", Secret: "JBSWY3DPEHPK3PXP",
+		Type: model.TypeTOTP, Digits: 6, Algorithm: "sha1", Period: 30,
+		Misc: map[string]interface{}{"tags": []interface{}{"work", "dev"}},
+	}
+	hotp := model.Account{
+		Issuer: "Bank", Label: `say "hi", ok`, Secret: "JBSWY3DPEHPK3PXP",
+		Type: model.TypeHOTP, Digits: 8, Algorithm: "sha256", Counter: 4,
+	}
+
+	codes := []Code{
+		NewCode(&totp, &model.OTPResult{Code: "123456", Remaining: 12, PeriodStart: 1700000010, PeriodEnd: 1700000040}),
+		NewCode(&hotp, &model.OTPResult{Code: "12345678"}),
+	}
+	accounts := []Account{NewAccount(1, &totp), NewAccount(2, &hotp)}
+	return codes, accounts
+}
+
+func TestWriteCodes(t *testing.T) {
+	codes, _ := testViews()
+
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{CSV, `issuer,label,type,code,remaining,period_start,period_end
+GitHub,me,totp,123456,12,1700000010,1700000040
+Bank,"say ""hi"", ok",hotp,12345678,,,
+`},
+		{TSV, "issuer\tlabel\ttype\tcode\tremaining\tperiod_start\tperiod_end\n" +
+			"GitHub\tme\ttotp\t123456\t12\t1700000010\t1700000040\n" +
+			"Bank\t\"say \"\"hi\"\", ok\"\thotp\t12345678\t\t\t\n"},
+		{YAML, `- issuer: "GitHub"
+  label: "me"
+  type: "totp"
+  code: "123456"
+  remaining: 12
+  period_start: 1700000010
+  period_end: 1700000040
+- issuer: "Bank"
+  label: "say \"hi\", ok"
+  type: "hotp"
+  code: "12345678"
+  remaining: null
+  period_start: null
+  period_end: null
+`},
+		{JSON, `[
+  {
+    "issuer": "GitHub",
+    "label": "me",
+    "type": "totp",
+    "code": "123456",
+    "remaining": 12,
+    "period_start": 1700000010,
+    "period_end": 1700000040
+  },
+  {
+    "issuer": "Bank",
+    "label": "say \"hi\", ok",
+    "type": "hotp",
+    "code": "12345678"
+  }
+]
+`},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Write(&buf, tt.format, codes); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s output:\n%s\nwant:\n%s", tt.format, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestWriteAccountsOmitsSecrets(t *testing.T) {
+	_, accounts := testViews()
+
+	for _, f := range Formats {
+		var buf bytes.Buffer
+		if err := Write(&buf, f, accounts); err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		out := buf.String()
+		if strings.Contains(out, "JBSWY3DPEHPK3PXP") {
+			t.Errorf("%s output leaks the secret:\n%s", f, out)
+		}
+		if !strings.Contains(out, "sha256") || !strings.Contains(out, "work") {
+			t.Errorf("%s output misses algorithm or tags:\n%s", f, out)
+		}
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	for f, want := range map[Format]string{JSON: "[]\n", YAML: "[]\n", CSV: "", Plain: ""} {
+		var buf bytes.Buffer
+		if err := Write(&buf, f, []Code(nil)); err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		if buf.String() != want {
+			t.Errorf("%s: got %q, want %q", f, buf.String(), want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("JSON"); err != nil || f != JSON {
+		t.Errorf("ParseFormat(JSON) = %q, %v", f, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(xml) should fail")
+	}
+}
+
+func TestWriteTemplate(t *testing.T) {
+	codes, accounts := testViews()
+
+	tmpl, err := ParseTemplate(`{{pad 8 .Issuer}}{{.Label}} {{.Code}} ({{.Remaining}}s) {{upper .Type}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteTemplate(&buf, tmpl, codes); err != nil {
+		t.Fatal(err)
+	}
+	want := "GitHub  me 123456 (12s) TOTP\nBank    say \"hi\", ok 12345678 (0s) HOTP\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	tmpl, _ = ParseTemplate(`{{padLeft 3 .ID}} {{join "," .Tags}}`)
+	buf.Reset()
+	if err := WriteTemplate(&buf, tmpl, accounts[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "  1 work,dev\n" {
+		t.Errorf("got %q", buf.String())
+	}
+
+	tmpl, _ = ParseTemplate(`{{.Secret}}`)
+	if err := WriteTemplate(&buf, tmpl, accounts); err == nil {
+		t.Error("templates must not reach fields outside the view")
+	}
+	if _, err := ParseTemplate(`{{.Code`); err == nil {
+		t.Error("ParseTemplate accepted a broken template")
+	}
+}
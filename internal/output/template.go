@@ -0,0 +1,79 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// TemplateHelp documents the --format fields and helpers for help texts.
+const TemplateHelp = `Templates use Go text/template syntax and are rendered once per line.
+
+Code fields:    .Issuer .Label .Type .Code .Remaining .PeriodStart .PeriodEnd
+Account fields: .ID .Issuer .Label .Type .Digits .Algorithm .Period .Counter .Tags
+
+.Remaining and the period bounds are 0 for HOTP codes. An account has either
+.Period or .Counter; guard them with {{with .Counter}}...{{end}}.
+
+Helpers:
+  pad N s       pad s with spaces on the right to N characters
+  padLeft N s   pad s with spaces on the left to N characters
+  upper s       upper-case s
+  lower s       lower-case s
+  join sep list join a list such as .Tags
+  color C s     color s with an ANSI color name (red, green, ...), number or
+                hex code; dropped when not writing to a terminal
+`
+
+var colorNames = map[string]string{
+	"black": "0", "red": "1", "green": "2", "yellow": "3",
+	"blue": "4", "magenta": "5", "cyan": "6", "white": "7",
+}
+
+var templateFuncs = template.FuncMap{
+	"pad": func(n int, v interface{}) string {
+		return fmt.Sprintf("%-*s", n, fmt.Sprint(v))
+	},
+	"padLeft": func(n int, v interface{}) string {
+		return fmt.Sprintf("%*s", n, fmt.Sprint(v))
+	},
+	"upper": func(v interface{}) string { return strings.ToUpper(fmt.Sprint(v)) },
+	"lower": func(v interface{}) string { return strings.ToLower(fmt.Sprint(v)) },
+	"join":  func(sep string, list []string) string { return strings.Join(list, sep) },
+	"color": func(c string, v interface{}) string {
+		if n, ok := colorNames[strings.ToLower(c)]; ok {
+			c = n
+		}
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(c)).Render(fmt.Sprint(v))
+	},
+}
+
+// ParseTemplate compiles a --format template.
+func ParseTemplate(text string) (*template.Template, error) {
+	t, err := template.New("format").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --format template: %w", err)
+	}
+	return t, nil
+}
+
+// WriteTemplate renders every record with t, each on its own line.
+func WriteTemplate[T any](w io.Writer, t *template.Template, records []T) error {
+	for _, r := range records {
+		var b strings.Builder
+		if err := t.Execute(&b, r); err != nil {
+			return fmt.Errorf("--format: %w", err)
+		}
+		line := b.String()
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package output
+
+import (
+	"strings"
+
+	"github.com/leeineian/gauth/internal/model"
+)
+
+// Code is the machine-readable view of a generated code. Remaining,
+// PeriodStart and PeriodEnd (Unix seconds) are zero for HOTP accounts.
+type Code struct {
+	Issuer      string `json:"issuer"`
+	Label       string `json:"label"`
+	Type        string `json:"type"`
+	Code        string `json:"code"`
+	Remaining   int64  `json:"remaining,omitempty"`
+	PeriodStart int64  `json:"period_start,omitempty"`
+	PeriodEnd   int64  `json:"period_end,omitempty"`
+}
+
+// NewCode builds the view of res, the current code of acc.
+func NewCode(acc *model.Account, res *model.OTPResult) Code {
+	return Code{
+		Issuer:      acc.Issuer,
+		Label:       acc.DisplayLabel(),
+		Type:        strings.ToLower(string(acc.Type)),
+		Code:        res.Code,
+		Remaining:   res.Remaining,
+		PeriodStart: res.PeriodStart,
+		PeriodEnd:   res.PeriodEnd,
+	}
+}
+
+func (c Code) Fields() []Field {
+	return []Field{
+		{"issuer", c.Issuer},
+		{"label", c.Label},
+		{"type", c.Type},
+		{"code", c.Code},
+		{"remaining", optional(c.Remaining)},
+		{"period_start", optional(c.PeriodStart)},
+		{"period_end", optional(c.PeriodEnd)},
+	}
+}
+
+// Account is the machine-readable view of an account. It deliberately has
+// no room for the secret. Period is only set for time-based accounts and
+// Counter only for HOTP ones.
+type Account struct {
+	ID        int      `json:"id"`
+	Issuer    string   `json:"issuer"`
+	Label     string   `json:"label"`
+	Type      string   `json:"type"`
+	Digits    int      `json:"digits"`
+	Algorithm string   `json:"algorithm"`
+	Period    *int64   `json:"period,omitempty"`
+	Counter   *int64   `json:"counter,omitempty"`
+	Tags      []string `json:"tags"`
+}
+
+// NewAccount builds the view of acc, listed at position id (1-based).
+func NewAccount(id int, acc *model.Account) Account {
+	v := Account{
+		ID:        id,
+		Issuer:    acc.Issuer,
+		Label:     acc.DisplayLabel(),
+		Type:      strings.ToLower(string(acc.Type)),
+		Digits:    acc.Digits,
+		Algorithm: strings.ToLower(acc.Algorithm),
+		Tags:      acc.Tags(),
+	}
+	if v.Tags == nil {
+		v.Tags = []string{}
+	}
+	if acc.Type.IsTimeBased() {
+		period := acc.Period
+		if period == 0 {
+			period = model.DefaultPeriod
+		}
+		v.Period = &period
+	} else {
+		counter := acc.Counter
+		v.Counter = &counter
+	}
+	return v
+}
+
+func (a Account) Fields() []Field {
+	return []Field{
+		{"id", a.ID},
+		{"issuer", a.Issuer},
+		{"label", a.Label},
+		{"type", a.Type},
+		{"digits", a.Digits},
+		{"algorithm", a.Algorithm},
+		{"period", deref(a.Period)},
+		{"counter", deref(a.Counter)},
+		{"tags", a.Tags},
+	}
+}
+
+// deref maps a missing value to nil.
+func deref(v *int64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// optional maps zero to nil, which is written as an empty cell or null.
+func optional(v int64) interface{} {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,470 @@
+package aegis
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/provider"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	vaultVersion = 1
+	dbVersion    = 3
+
+	slotTypePassword = 1
+
+	// Aegis' own defaults for new password slots
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	// Caps on the scrypt costs a vault may ask for, so a corrupted or hostile
+	// file can't exhaust the machine before the password is checked
+	maxScryptMemory = 1 << 30 // 128·r·N bytes, 1 GiB
+	maxScryptP      = 16
+
+	keyLen   = 32
+	nonceLen = 12
+	tagLen   = 16
+)
+
+type vault struct {
+	Version int             `json:"version"`
+	Header  header          `json:"header"`
+	DB      json.RawMessage `json:"db"`
+}
+
+type header struct {
+	Slots  []slot     `json:"slots"`
+	Params *keyParams `json:"params"`
+}
+
+type keyParams struct {
+	Nonce string `json:"nonce"`
+	Tag   string `json:"tag"`
+}
+
+type slot struct {
+	Type      int        `json:"type"`
+	UUID      string     `json:"uuid"`
+	Key       string     `json:"key"`
+	KeyParams *keyParams `json:"key_params"`
+	N         int        `json:"n,omitempty"`
+	R         int        `json:"r,omitempty"`
+	P         int        `json:"p,omitempty"`
+	Salt      string     `json:"salt,omitempty"`
+	Repaired  bool       `json:"repaired,omitempty"`
+	IsBackup  bool       `json:"is_backup,omitempty"`
+}
+
+type database struct {
+	Version int     `json:"version"`
+	Entries []entry `json:"entries"`
+	Groups  []group `json:"groups,omitempty"`
+}
+
+type group struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+type entry struct {
+	Type     string   `json:"type"`
+	UUID     string   `json:"uuid"`
+	Name     string   `json:"name"`
+	Issuer   string   `json:"issuer"`
+	Note     string   `json:"note"`
+	Favorite bool     `json:"favorite"`
+	Icon     *string  `json:"icon"`
+	IconMime *string  `json:"icon_mime,omitempty"`
+	Info     info     `json:"info"`
+	Groups   []string `json:"groups,omitempty"`
+	Group    *string  `json:"group,omitempty"` // db version 2 and older
+}
+
+type info struct {
+	Secret  string `json:"secret"`
+	Algo    string `json:"algo"`
+	Digits  int    `json:"digits"`
+	Period  int64  `json:"period,omitempty"`
+	Counter *int64 `json:"counter,omitempty"`
+}
+
+type Provider struct{}
+
+func New() *Provider {
+	return &Provider{}
+}
+
+func init() {
+	provider.Register(New())
+}
+
+func (p *Provider) Name() string {
+	return "aegis"
+}
+
+func (p *Provider) SupportsEncryption() bool {
+	return true
+}
+
+// Detect recognizes the vault envelope shared by plain and encrypted vaults.
+func (p *Provider) Detect(data []byte) bool {
+	var v map[string]json.RawMessage
+	if err := json.Unmarshal(data, &v); err != nil {
+		return false
+	}
+	_, hasHeader := v["header"]
+	_, hasDB := v["db"]
+	return hasHeader && hasDB
+}
+
+func (p *Provider) Import(data []byte, password string) ([]model.Account, error) {
+	var v vault
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("invalid Aegis vault: %w", err)
+	}
+	if v.Version != vaultVersion {
+		return nil, fmt.Errorf("unsupported Aegis vault version %d", v.Version)
+	}
+
+	plain := []byte(v.DB)
+	if len(v.Header.Slots) > 0 {
+		if password == "" {
+			return nil, provider.ErrPasswordRequired
+		}
+		var err error
+		plain, err = decryptDB(&v, password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var db database
+	if err := json.Unmarshal(plain, &db); err != nil {
+		return nil, fmt.Errorf("invalid Aegis database: %w", err)
+	}
+
+	groupNames := make(map[string]string, len(db.Groups))
+	for _, g := range db.Groups {
+		groupNames[g.UUID] = g.Name
+	}
+
+	accounts := make([]model.Account, 0, len(db.Entries))
+	for _, e := range db.Entries {
+		accounts = append(accounts, *e.toAccount(groupNames))
+	}
+	return accounts, nil
+}
+
+// toAccount converts an entry. Types gauth can't generate, such as motp or
+// yandex, are kept as they are so the import preview lists them as invalid
+// instead of failing the whole vault.
+func (e *entry) toAccount(groupNames map[string]string) *model.Account {
+	acc := &model.Account{
+		Secret:    e.Info.Secret,
+		Label:     e.Name,
+		Issuer:    e.Issuer,
+		Digits:    e.Info.Digits,
+		Algorithm: strings.ToLower(e.Info.Algo),
+		Period:    e.Info.Period,
+		Misc:      map[string]interface{}{},
+	}
+
+	switch strings.ToLower(e.Type) {
+	case "totp":
+		acc.Type = model.TypeTOTP
+	case "hotp":
+		acc.Type = model.TypeHOTP
+	case "steam":
+		acc.Type = model.TypeSteam
+	default:
+		acc.Type = model.OTPType(strings.ToLower(e.Type))
+	}
+	if e.Info.Counter != nil {
+		acc.Counter = *e.Info.Counter
+	}
+
+	var groups []interface{}
+	for _, id := range e.Groups {
+		if name, ok := groupNames[id]; ok {
+			groups = append(groups, name)
+		}
+	}
+	if e.Group != nil && *e.Group != "" {
+		groups = append(groups, *e.Group)
+	}
+
+	if e.UUID != "" {
+		acc.Misc["uuid"] = e.UUID
+	}
+	if e.Note != "" {
+		acc.Misc["note"] = e.Note
+	}
+	if e.Favorite {
+		acc.Misc["favorite"] = true
+	}
+	if e.Icon != nil && *e.Icon != "" {
+		acc.Misc["icon"] = *e.Icon
+		if e.IconMime != nil {
+			acc.Misc["icon_mime"] = *e.IconMime
+		}
+	}
+	if len(groups) > 0 {
+		acc.Misc["groups"] = groups
+	}
+	if len(acc.Misc) == 0 {
+		acc.Misc = nil
+	}
+
+	return acc
+}
+
+func (p *Provider) Export(accounts []model.Account, password string) ([]byte, error) {
+	db := database{Version: dbVersion, Entries: make([]entry, 0, len(accounts))}
+	groupIDs := make(map[string]string)
+
+	for _, a := range accounts {
+		e := entry{
+			Type:   strings.ToLower(string(a.Type)),
+			UUID:   a.MiscString("uuid"),
+			Name:   a.DisplayLabel(),
+			Issuer: a.Issuer,
+			Note:   a.MiscString("note"),
+			Info: info{
+				Secret: a.Secret,
+				Algo:   strings.ToUpper(a.Algorithm),
+				Digits: a.Digits,
+				Period: a.Period,
+			},
+		}
+		if e.Type == "" {
+			e.Type = string(model.DefaultType)
+		}
+		if e.Info.Algo == "" {
+			e.Info.Algo = strings.ToUpper(model.DefaultAlgo)
+		}
+		if e.UUID == "" {
+			e.UUID = provider.NewUUID()
+		}
+		if fav, ok := a.Misc["favorite"].(bool); ok {
+			e.Favorite = fav
+		}
+		if icon := a.MiscString("icon"); icon != "" {
+			mime := a.MiscString("icon_mime")
+			e.Icon, e.IconMime = &icon, &mime
+		}
+		if a.Type.IsHOTP() {
+			counter := a.Counter
+			e.Info.Counter = &counter
+			e.Info.Period = 0
+		}
+
+		for _, name := range a.MiscStrings("groups") {
+			id, ok := groupIDs[name]
+			if !ok {
+				id = provider.NewUUID()
+				groupIDs[name] = id
+				db.Groups = append(db.Groups, group{UUID: id, Name: name})
+			}
+			e.Groups = append(e.Groups, id)
+		}
+
+		db.Entries = append(db.Entries, e)
+	}
+
+	plain, err := json.Marshal(db)
+	if err != nil {
+		return nil, err
+	}
+
+	v := vault{Version: vaultVersion, DB: plain}
+	if password != "" {
+		if err := encryptDB(&v, plain, password); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.MarshalIndent(v, "", "    ")
+}
+
+// decryptDB unlocks the master key through the first matching password slot
+// and uses it to decrypt the database.
+func decryptDB(v *vault, password string) ([]byte, error) {
+	if v.Header.Params == nil {
+		return nil, fmt.Errorf("invalid Aegis vault: missing database parameters")
+	}
+
+	var masterKey []byte
+	for _, s := range v.Header.Slots {
+		if s.Type != slotTypePassword || s.KeyParams == nil {
+			continue
+		}
+
+		if err := s.validateScrypt(); err != nil {
+			return nil, err
+		}
+		salt, err := hex.DecodeString(s.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Aegis slot salt: %w", err)
+		}
+		derived, err := scrypt.Key([]byte(password), salt, s.N, s.R, s.P, keyLen)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Aegis slot parameters: %w", err)
+		}
+
+		key, err := hex.DecodeString(s.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Aegis slot key: %w", err)
+		}
+		if masterKey, err = open(derived, key, s.KeyParams); err == nil {
+			break
+		}
+	}
+	if masterKey == nil {
+		return nil, fmt.Errorf("failed to decrypt Aegis vault (wrong password?)")
+	}
+
+	var encoded string
+	if err := json.Unmarshal(v.DB, &encoded); err != nil {
+		return nil, fmt.Errorf("invalid Aegis vault: encrypted database is not a string")
+	}
+	cipherText, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Aegis vault: %w", err)
+	}
+
+	plain, err := open(masterKey, cipherText, v.Header.Params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt Aegis database: %w", err)
+	}
+	return plain, nil
+}
+
+// validateScrypt checks that the slot's scrypt costs are usable and within
+// sane bounds.
+func (s slot) validateScrypt() error {
+	if s.N < 2 || s.N&(s.N-1) != 0 {
+		return fmt.Errorf("invalid Aegis slot parameters: scrypt N must be a power of two above 1, got %d", s.N)
+	}
+	if s.R < 1 || s.P < 1 {
+		return fmt.Errorf("invalid Aegis slot parameters: scrypt r and p must be at least 1")
+	}
+	if s.P > maxScryptP {
+		return fmt.Errorf("invalid Aegis slot parameters: scrypt p of %d exceeds the limit of %d", s.P, maxScryptP)
+	}
+	if s.R > maxScryptMemory/128/s.N {
+		return fmt.Errorf("invalid Aegis slot parameters: scrypt N=%d, r=%d needs more than %d MiB", s.N, s.R, maxScryptMemory>>20)
+	}
+	return nil
+}
+
+// encryptDB seals plain under a fresh master key wrapped by a single scrypt
+// password slot, the layout Aegis writes for password-protected exports.
+func encryptDB(v *vault, plain []byte, password string) error {
+	masterKey := make([]byte, keyLen)
+	salt := make([]byte, keyLen)
+	if _, err := rand.Read(masterKey); err != nil {
+		return err
+	}
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	derived, err := scrypt.Key([]byte(password), salt, scryptN, scryptR, scryptP, keyLen)
+	if err != nil {
+		return err
+	}
+
+	wrapped, slotParams, err := seal(derived, masterKey)
+	if err != nil {
+		return err
+	}
+	cipherText, dbParams, err := seal(masterKey, plain)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(base64.StdEncoding.EncodeToString(cipherText))
+	if err != nil {
+		return err
+	}
+
+	v.Header = header{
+		Slots: []slot{{
+			Type:      slotTypePassword,
+			UUID:      provider.NewUUID(),
+			Key:       hex.EncodeToString(wrapped),
+			KeyParams: slotParams,
+			N:         scryptN,
+			R:         scryptR,
+			P:         scryptP,
+			Salt:      hex.EncodeToString(salt),
+			Repaired:  true,
+		}},
+		Params: dbParams,
+	}
+	v.DB = encoded
+	return nil
+}
+
+// open decrypts data whose GCM tag Aegis stores separately in params.
+func open(key, data []byte, params *keyParams) ([]byte, error) {
+	nonce, err := hex.DecodeString(params.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := hex.DecodeString(params.Tag)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(nonce))
+	}
+
+	sealed := make([]byte, 0, len(data)+len(tag))
+	sealed = append(sealed, data...)
+	sealed = append(sealed, tag...)
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+// seal encrypts data and splits the GCM tag off into params.
+func seal(key, data []byte) ([]byte, *keyParams, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, nonceLen)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	sealed := gcm.Seal(nil, nonce, data, nil)
+	cut := len(sealed) - tagLen
+	return sealed[:cut], &keyParams{
+		Nonce: hex.EncodeToString(nonce),
+		Tag:   hex.EncodeToString(sealed[cut:]),
+	}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,177 @@
+package aegis
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/provider"
+	"github.com/leeineian/gauth/internal/service"
+)
+
+var testAccounts = []model.Account{
+	{
+		Secret: "JBSWY3DPEHPK3PXP", Issuer: "GitHub", Label: "octocat", Type: model.TypeTOTP,
+		Digits: 6, Algorithm: "sha1", Period: 30,
+		Misc: map[string]interface{}{
+			"uuid":      "0f0e0d0c-0b0a-4908-8706-050403020100",
+			"note":      "work account",
+			"favorite":  true,
+			"icon":      "iVBORw0KGgo=",
+			"icon_mime": "image/png",
+			"groups":    []interface{}{"Work", "Dev"},
+		},
+	},
+	{
+		Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Issuer: "Corp VPN", Label: "ops", Type: model.TypeHOTP,
+		Digits: 8, Algorithm: "sha256", Counter: 42,
+		Misc: map[string]interface{}{
+			"uuid":   "1f1e1d1c-1b1a-4918-9716-151413121110",
+			"groups": []interface{}{"Work"},
+		},
+	},
+	{
+		Secret: "JBSWY3DPEHPK3PXP", Issuer: "Steam", Label: "gamer", Type: model.TypeSteam,
+		Digits: 5, Algorithm: "sha1", Period: 30,
+		Misc: map[string]interface{}{
+			"uuid": "2f2e2d2c-2b2a-4928-a726-252423222120",
+		},
+	},
+}
+
+func roundTrip(t *testing.T, password string) []model.Account {
+	t.Helper()
+
+	p := New()
+	data, err := p.Export(testAccounts, password)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if !p.Detect(data) {
+		t.Error("Detect() = false for exported vault")
+	}
+
+	accounts, err := p.Import(data, password)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	return accounts
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, password := range []string{"", "correct horse"} {
+		accounts := roundTrip(t, password)
+		if !reflect.DeepEqual(accounts, testAccounts) {
+			t.Errorf("round trip (password %q) mismatch:\ngot  %+v\nwant %+v", password, accounts, testAccounts)
+		}
+	}
+}
+
+func TestWrongPassword(t *testing.T) {
+	p := New()
+	data, err := p.Export(testAccounts, "secret")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if _, err := p.Import(data, "wrong"); err == nil {
+		t.Error("expected error with wrong password, got nil")
+	}
+	if _, err := p.Import(data, ""); !errors.Is(err, provider.ErrPasswordRequired) {
+		t.Errorf("expected ErrPasswordRequired without password, got %v", err)
+	}
+}
+
+// Scrypt costs come from the file and must be checked before deriving a key.
+func TestHostileScryptParams(t *testing.T) {
+	p := New()
+	data, err := p.Export(testAccounts, "secret")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	for _, costs := range [][3]int{{1 << 30, 8, 1}, {3 << 14, 8, 1}, {1 << 15, 8, 1 << 20}, {1 << 15, 0, 1}, {0, 8, 1}} {
+		var v vault
+		if err := json.Unmarshal(data, &v); err != nil {
+			t.Fatal(err)
+		}
+		v.Header.Slots[0].N, v.Header.Slots[0].R, v.Header.Slots[0].P = costs[0], costs[1], costs[2]
+		hostile, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Import(hostile, "secret"); err == nil || !strings.Contains(err.Error(), "scrypt") {
+			t.Errorf("N=%d, r=%d, p=%d: got %v", costs[0], costs[1], costs[2], err)
+		}
+	}
+}
+
+// Aegis 2.x wrote a single "group" name instead of group UUIDs.
+func TestImportLegacyGroup(t *testing.T) {
+	vault := `{"version":1,"header":{"slots":null,"params":null},"db":{"version":2,"entries":[
+		{"type":"totp","uuid":"u1","name":"alice","issuer":"Example","group":"Personal","icon":null,
+		 "info":{"secret":"JBSWY3DPEHPK3PXP","algo":"SHA1","digits":6,"period":30}}]}}`
+
+	accounts, err := New().Import([]byte(vault), "")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(accounts) != 1 {
+		t.Fatalf("expected 1 account, got %d", len(accounts))
+	}
+	if got := accounts[0].MiscStrings("groups"); !reflect.DeepEqual(got, []string{"Personal"}) {
+		t.Errorf("expected groups [Personal], got %v", got)
+	}
+}
+
+// One entry of a type gauth can't generate must not block the others.
+func TestImportUnsupportedType(t *testing.T) {
+	vault := `{"version":1,"header":{"slots":null,"params":null},"db":{"version":2,"entries":[
+		{"type":"totp","uuid":"u1","name":"alice","issuer":"Example","icon":null,
+		 "info":{"secret":"JBSWY3DPEHPK3PXP","algo":"SHA1","digits":6,"period":30}},
+		{"type":"motp","uuid":"u2","name":"bob","issuer":"Legacy","icon":null,
+		 "info":{"secret":"JBSWY3DPEHPK3PXP","algo":"MD5","digits":6,"period":10,"pin":"1234"}},
+		{"type":"yandex","uuid":"u3","name":"carol","issuer":"Yandex","icon":null,
+		 "info":{"secret":"JBSWY3DPEHPK3PXP","algo":"SHA256","digits":8,"period":30,"pin":"5678"}},
+		{"type":"hotp","uuid":"u4","name":"dave","issuer":"Corp","icon":null,
+		 "info":{"secret":"JBSWY3DPEHPK3PXP","algo":"SHA1","digits":6,"counter":3}}]}}`
+
+	accounts, err := New().Import([]byte(vault), "")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	plan := service.PlanImport(nil, accounts)
+	want := []service.ImportStatus{service.ImportNew, service.ImportInvalid, service.ImportInvalid, service.ImportNew}
+	if len(plan) != len(want) {
+		t.Fatalf("expected %d plan entries, got %d", len(want), len(plan))
+	}
+	for i, e := range plan {
+		if e.Status != want[i] {
+			t.Errorf("%s: status %s, want %s (%s)", e.Account.FullIdentifier(), e.Status, want[i], e.Detail)
+		}
+	}
+	if !strings.Contains(plan[1].Detail, "motp") || !strings.Contains(plan[2].Detail, "yandex") {
+		t.Errorf("invalid entries should name their type: %q, %q", plan[1].Detail, plan[2].Detail)
+	}
+}
+
+// Older vaults store types upper-case and export doesn't normalize them.
+func TestExportUpperCaseHOTP(t *testing.T) {
+	acc := model.Account{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Bank", Label: "me", Type: "HOTP", Digits: 6, Algorithm: "sha1", Period: 30, Counter: 42}
+	p := New()
+	data, err := p.Export([]model.Account{acc}, "")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	accounts, err := p.Import(data, "")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if got := accounts[0]; got.Type != model.TypeHOTP || got.Counter != 42 || got.Period != 0 {
+		t.Errorf("round trip got %+v", got)
+	}
+}
@@ -2,24 +2,33 @@ package andotp
 
 import (
 	"encoding/json"
-	"os"
+	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	ga "github.com/grijul/go-andotp/andotp"
 	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/provider"
 )
 
+// andotpNode mirrors an entry of andOTP's backup format. Period is only
+// written for time-based entries and Counter only for HOTP ones.
 type andotpNode struct {
-	Secret         string        `json:"secret"`
-	Issuer         string        `json:"issuer"`
-	Label          string        `json:"label"`
-	Digits         int           `json:"digits"`
-	Type           string        `json:"type"`
-	Algorithm      string        `json:"alogrithm"`
-	Thumbnail      string        `json:"thumbnail"`
-	Last_used      int64         `json:"last_used"`
-	Used_frequency int           `json:"used_frequency"`
-	Period         int           `json:"period"`
-	Tags           []interface{} `json:"tags"`
+	Secret        string   `json:"secret"`
+	Issuer        string   `json:"issuer"`
+	Label         string   `json:"label"`
+	Digits        int      `json:"digits"`
+	Type          string   `json:"type"`
+	Algorithm     string   `json:"algorithm"`
+	Thumbnail     string   `json:"thumbnail"`
+	LastUsed      int64    `json:"last_used"`
+	UsedFrequency int64    `json:"used_frequency"`
+	Period        *int64   `json:"period,omitempty"`
+	Counter       *int64   `json:"counter,omitempty"`
+	Tags          []string `json:"tags"`
+
+	// Older gauth versions wrote the algorithm under a misspelled key
+	LegacyAlgorithm string `json:"alogrithm,omitempty"`
 }
 
 type Provider struct{}
@@ -28,44 +37,81 @@ func New() *Provider {
 	return &Provider{}
 }
 
-func (p *Provider) Import(filePath string, password string) ([]model.Account, error) {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, err
+func init() {
+	provider.Register(New())
+}
+
+func (p *Provider) Name() string {
+	return "andotp"
+}
+
+func (p *Provider) SupportsEncryption() bool {
+	return true
+}
+
+// Detect recognizes plain andOTP backups, a JSON array of entries, and
+// encrypted ones, which are opaque binary rather than text.
+func (p *Provider) Detect(data []byte) bool {
+	if !json.Valid(data) {
+		return !utf8.Valid(data)
+	}
+
+	var nodes []map[string]interface{}
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return false
+	}
+	for _, n := range nodes {
+		if _, ok := n["secret"]; !ok {
+			return false
+		}
 	}
+	return true
+}
 
+func (p *Provider) Import(data []byte, password string) ([]model.Account, error) {
 	var nodes []andotpNode
 	if err := json.Unmarshal(data, &nodes); err != nil {
-		if password != "" {
-			decrypted, err := ga.Decrypt(data, password)
-			if err != nil {
-				return nil, err
-			}
-			if err := json.Unmarshal(decrypted, &nodes); err != nil {
-				return nil, err
-			}
-		} else {
+		if json.Valid(data) {
+			return nil, err
+		}
+		if password == "" {
+			return nil, provider.ErrPasswordRequired
+		}
+		decrypted, err := ga.Decrypt(data, password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt andOTP backup (wrong password?): %w", err)
+		}
+		if err := json.Unmarshal(decrypted, &nodes); err != nil {
 			return nil, err
 		}
 	}
 
 	accounts := make([]model.Account, 0, len(nodes))
 	for _, n := range nodes {
-		accounts = append(accounts, model.Account{
+		acc := model.Account{
 			Secret:    n.Secret,
 			Issuer:    n.Issuer,
 			Label:     n.Label,
 			Digits:    n.Digits,
-			Type:      model.OTPType(n.Type),
-			Algorithm: n.Algorithm,
-			Period:    int64(n.Period),
+			Type:      model.OTPType(strings.ToLower(n.Type)),
+			Algorithm: strings.ToLower(n.Algorithm),
 			Misc: map[string]interface{}{
 				"thumbnail":      n.Thumbnail,
-				"last_used":      n.Last_used,
-				"used_frequency": n.Used_frequency,
-				"tags":           n.Tags,
+				"last_used":      n.LastUsed,
+				"used_frequency": n.UsedFrequency,
+				"tags":           tagsToMisc(n.Tags),
 			},
-		})
+		}
+		if acc.Algorithm == "" {
+			acc.Algorithm = strings.ToLower(n.LegacyAlgorithm)
+		}
+		if n.Period != nil {
+			acc.Period = *n.Period
+		}
+		if n.Counter != nil {
+			acc.Counter = *n.Counter
+		}
+		accounts = append(accounts, acc)
 	}
 
 	return accounts, nil
@@ -79,23 +125,36 @@ func (p *Provider) Export(accounts []model.Account, password string) ([]byte, er
 			Issuer:    a.Issuer,
 			Label:     a.Label,
 			Digits:    a.Digits,
-			Type:      string(a.Type),
-			Algorithm: a.Algorithm,
-			Period:    int(a.Period),
+			Type:      strings.ToUpper(string(a.Type)),
+			Algorithm: strings.ToUpper(a.Algorithm),
+			Thumbnail: a.MiscString("thumbnail"),
+			Tags:      a.MiscStrings("tags"),
 		}
-		if a.Misc != nil {
-			if v, ok := a.Misc["thumbnail"].(string); ok {
-				node.Thumbnail = v
-			}
-			if v, ok := a.Misc["last_used"].(int64); ok {
-				node.Last_used = v
-			}
-			if v, ok := a.Misc["used_frequency"].(int); ok {
-				node.Used_frequency = v
-			}
-			if v, ok := a.Misc["tags"].([]interface{}); ok {
-				node.Tags = v
+		if node.Type == "" {
+			node.Type = strings.ToUpper(string(model.DefaultType))
+		}
+		if node.Algorithm == "" {
+			node.Algorithm = strings.ToUpper(model.DefaultAlgo)
+		}
+		if node.Tags == nil {
+			node.Tags = []string{}
+		}
+		if v, ok := a.MiscInt("last_used"); ok {
+			node.LastUsed = v
+		}
+		if v, ok := a.MiscInt("used_frequency"); ok {
+			node.UsedFrequency = v
+		}
+
+		if a.Type.IsHOTP() {
+			counter := a.Counter
+			node.Counter = &counter
+		} else {
+			period := a.Period
+			if period == 0 {
+				period = model.DefaultPeriod
 			}
+			node.Period = &period
 		}
 		nodes = append(nodes, node)
 	}
@@ -111,3 +170,13 @@ func (p *Provider) Export(accounts []model.Account, password string) ([]byte, er
 
 	return data, nil
 }
+
+// tagsToMisc stores tags in the shape they have once the vault is reloaded
+// from JSON.
+func tagsToMisc(tags []string) []interface{} {
+	out := make([]interface{}, 0, len(tags))
+	for _, t := range tags {
+		out = append(out, t)
+	}
+	return out
+}
@@ -0,0 +1,159 @@
+package andotp
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/provider"
+)
+
+// Every field andOTP defines, for each entry type it supports
+const sampleBackup = `[
+  {"secret":"JBSWY3DPEHPK3PXP","issuer":"GitHub","label":"octocat","digits":6,"type":"TOTP","algorithm":"SHA1",
+   "thumbnail":"Github","last_used":1700000000123,"used_frequency":17,"period":30,"tags":["Work","Dev"]},
+  {"secret":"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ","issuer":"Corp VPN","label":"ops","digits":8,"type":"HOTP","algorithm":"SHA256",
+   "thumbnail":"Default","last_used":0,"used_frequency":0,"counter":0,"tags":[]},
+  {"secret":"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ","issuer":"Bank","label":"me","digits":7,"type":"HOTP","algorithm":"SHA512",
+   "thumbnail":"Default","last_used":1600000000000,"used_frequency":3,"counter":42,"tags":["Finance"]},
+  {"secret":"JBSWY3DPEHPK3PXP","issuer":"Steam","label":"gamer","digits":5,"type":"STEAM","algorithm":"SHA1",
+   "thumbnail":"Steam","last_used":1650000000000,"used_frequency":2,"period":30,"tags":[]}
+]`
+
+func decodeNodes(t *testing.T, data []byte) []map[string]interface{} {
+	t.Helper()
+	var nodes []map[string]interface{}
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	return nodes
+}
+
+func TestImport(t *testing.T) {
+	accounts, err := New().Import([]byte(sampleBackup), "")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(accounts) != 4 {
+		t.Fatalf("expected 4 accounts, got %d", len(accounts))
+	}
+
+	gh := accounts[0]
+	if gh.Type != model.TypeTOTP || gh.Algorithm != "sha1" || gh.Period != 30 {
+		t.Errorf("unexpected account: %+v", gh)
+	}
+	if !reflect.DeepEqual(gh.MiscStrings("tags"), []string{"Work", "Dev"}) {
+		t.Errorf("unexpected tags: %v", gh.Misc["tags"])
+	}
+
+	bank := accounts[2]
+	if bank.Type != model.TypeHOTP || bank.Counter != 42 || bank.Algorithm != "sha512" || bank.Digits != 7 {
+		t.Errorf("unexpected account: %+v", bank)
+	}
+
+	if accounts[3].Type != model.TypeSteam {
+		t.Errorf("expected steam type, got %s", accounts[3].Type)
+	}
+}
+
+func TestRoundTripIsLossless(t *testing.T) {
+	p := New()
+	accounts, err := p.Import([]byte(sampleBackup), "")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	// Store and reload the accounts the way the vault does, which turns
+	// Misc numbers into float64 and lists into []interface{}.
+	vault, err := json.Marshal(accounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reloaded []model.Account
+	if err := json.Unmarshal(vault, &reloaded); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, accs := range map[string][]model.Account{"fresh": accounts, "reloaded": reloaded} {
+		data, err := p.Export(accs, "")
+		if err != nil {
+			t.Fatalf("%s: Export() error = %v", name, err)
+		}
+		if got, want := decodeNodes(t, data), decodeNodes(t, []byte(sampleBackup)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: export mismatch:\ngot  %v\nwant %v", name, got, want)
+		}
+	}
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	p := New()
+	accounts, err := p.Import([]byte(sampleBackup), "")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	data, err := p.Export(accounts, "correct horse")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if !p.Detect(data) {
+		t.Error("Detect() = false for encrypted backup")
+	}
+
+	if _, err := p.Import(data, ""); !errors.Is(err, provider.ErrPasswordRequired) {
+		t.Errorf("expected ErrPasswordRequired without password, got %v", err)
+	}
+	if _, err := p.Import(data, "wrong"); err == nil {
+		t.Error("expected error with wrong password, got nil")
+	}
+
+	decrypted, err := p.Import(data, "correct horse")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if !reflect.DeepEqual(decrypted, accounts) {
+		t.Errorf("encrypted round trip mismatch:\ngot  %+v\nwant %+v", decrypted, accounts)
+	}
+}
+
+// Backups written by older gauth versions used a misspelled algorithm key.
+func TestImportLegacyAlgorithmKey(t *testing.T) {
+	legacy := `[{"secret":"JBSWY3DPEHPK3PXP","issuer":"Old","label":"me","digits":6,"type":"totp","alogrithm":"sha256","period":30,"tags":null}]`
+
+	accounts, err := New().Import([]byte(legacy), "")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if accounts[0].Algorithm != "sha256" {
+		t.Errorf("expected sha256, got %q", accounts[0].Algorithm)
+	}
+}
+
+// Older vaults store types upper-case and export doesn't normalize them.
+func TestRoundTripUpperCaseHOTP(t *testing.T) {
+	vault := `[{"secret":"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ","issuer":"Bank","label":"me","digits":6,"algorithm":"sha1","counter":42,"period":0,"type":"HOTP"}]`
+	var accounts []model.Account
+	if err := json.Unmarshal([]byte(vault), &accounts); err != nil {
+		t.Fatal(err)
+	}
+
+	p := New()
+	data, err := p.Export(accounts, "")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	node := decodeNodes(t, data)[0]
+	if _, ok := node["period"]; ok || node["counter"] != float64(42) {
+		t.Errorf("exported %v, want counter 42 and no period", node)
+	}
+
+	imported, err := p.Import(data, "")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if got := imported[0]; got.Type != model.TypeHOTP || got.Counter != 42 {
+		t.Errorf("round trip got %+v", got)
+	}
+}
@@ -0,0 +1,142 @@
+package gmigration
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/service"
+)
+
+// BatchSize is the number of accounts Google Authenticator puts in a single
+// transfer QR code. Larger exports are split across several batches.
+const BatchSize = 10
+
+func appendVarintField(buf []byte, num int, v uint64) []byte {
+	buf = binary.AppendUvarint(buf, uint64(num<<3|wireVarint))
+	return binary.AppendUvarint(buf, v)
+}
+
+func appendBytesField(buf []byte, num int, v []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(num<<3|wireBytes))
+	buf = binary.AppendUvarint(buf, uint64(len(v)))
+	return append(buf, v...)
+}
+
+func encodeParameters(acc *model.Account) ([]byte, error) {
+	secret, err := service.DecodeSecret(acc.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", acc.FullIdentifier(), err)
+	}
+
+	var algo uint64
+	switch strings.ToLower(acc.Algorithm) {
+	case "", "sha1":
+		algo = algoSHA1
+	case "sha256":
+		algo = algoSHA256
+	case "sha512":
+		algo = algoSHA512
+	default:
+		return nil, fmt.Errorf("%s: algorithm %s cannot be exported to Google Authenticator", acc.FullIdentifier(), acc.Algorithm)
+	}
+
+	var digits uint64
+	switch acc.Digits {
+	case 0, 6:
+		digits = digitsSix
+	case 8:
+		digits = digitsEight
+	default:
+		return nil, fmt.Errorf("%s: Google Authenticator only supports 6 or 8 digits", acc.FullIdentifier())
+	}
+
+	var typ uint64
+	switch model.OTPType(strings.ToLower(string(acc.Type))) {
+	case model.TypeTOTP, "":
+		typ = typeTOTP
+		if acc.Period != 0 && acc.Period != model.DefaultPeriod {
+			return nil, fmt.Errorf("%s: Google Authenticator only supports %ds periods", acc.FullIdentifier(), model.DefaultPeriod)
+		}
+	case model.TypeHOTP:
+		typ = typeHOTP
+	default:
+		return nil, fmt.Errorf("%s: %s accounts cannot be exported to Google Authenticator", acc.FullIdentifier(), acc.Type)
+	}
+
+	name := acc.DisplayLabel()
+	if acc.Issuer != "" {
+		name = acc.Issuer + ":" + name
+	}
+
+	buf := appendBytesField(nil, paramSecret, secret)
+	buf = appendBytesField(buf, paramName, []byte(name))
+	buf = appendBytesField(buf, paramIssuer, []byte(acc.Issuer))
+	buf = appendVarintField(buf, paramAlgorithm, algo)
+	buf = appendVarintField(buf, paramDigits, digits)
+	buf = appendVarintField(buf, paramType, typ)
+	if acc.Type.IsHOTP() {
+		buf = appendVarintField(buf, paramCounter, uint64(acc.Counter))
+	}
+	return buf, nil
+}
+
+// Format encodes accounts as otpauth-migration:// URIs, split into batches
+// of BatchSize that share a random batch ID, like the app's own export.
+func Format(accounts []model.Account) ([]string, error) {
+	entries := make([][]byte, 0, len(accounts))
+	for _, a := range accounts {
+		e, err := encodeParameters(&a)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	var idBuf [4]byte
+	if _, err := rand.Read(idBuf[:]); err != nil {
+		return nil, err
+	}
+	batchID := binary.BigEndian.Uint32(idBuf[:]) & 0x7fffffff
+
+	batches := (len(entries) + BatchSize - 1) / BatchSize
+	uris := make([]string, 0, batches)
+	for i := 0; i < batches; i++ {
+		end := min((i+1)*BatchSize, len(entries))
+
+		var payload []byte
+		for _, e := range entries[i*BatchSize : end] {
+			payload = appendBytesField(payload, payloadOTPParameters, e)
+		}
+		payload = appendVarintField(payload, payloadVersion, 1)
+		payload = appendVarintField(payload, payloadBatchSize, uint64(batches))
+		payload = appendVarintField(payload, payloadBatchIndex, uint64(i))
+		payload = appendVarintField(payload, payloadBatchID, uint64(batchID))
+
+		data := base64.StdEncoding.EncodeToString(payload)
+		uris = append(uris, scheme+"://offline?data="+url.QueryEscape(data))
+	}
+	return uris, nil
+}
+
+// QRPayloads splits an export back into its batches, one QR code each.
+func (p *Provider) QRPayloads(exported []byte) []string {
+	return strings.Fields(string(exported))
+}
+
+// Export writes one otpauth-migration:// URI per batch, one per line.
+func (p *Provider) Export(accounts []model.Account, password string) ([]byte, error) {
+	if password != "" {
+		return nil, fmt.Errorf("Google Authenticator exports cannot be encrypted")
+	}
+
+	uris, err := Format(accounts)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.Join(uris, "\n") + "\n"), nil
+}
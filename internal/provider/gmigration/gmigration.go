@@ -0,0 +1,251 @@
+package gmigration
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/provider"
+)
+
+const scheme = "otpauth-migration"
+
+// Enum values from Google Authenticator's MigrationPayload message.
+const (
+	algoUnspecified = 0
+	algoSHA1        = 1
+	algoSHA256      = 2
+	algoSHA512      = 3
+	algoMD5         = 4
+
+	digitsUnspecified = 0
+	digitsSix         = 1
+	digitsEight       = 2
+
+	typeUnspecified = 0
+	typeHOTP        = 1
+	typeTOTP        = 2
+)
+
+// Field numbers of MigrationPayload.OtpParameters.
+const (
+	paramSecret    = 1
+	paramName      = 2
+	paramIssuer    = 3
+	paramAlgorithm = 4
+	paramDigits    = 5
+	paramType      = 6
+	paramCounter   = 7
+)
+
+// Field numbers of MigrationPayload.
+const (
+	payloadOTPParameters = 1
+	payloadVersion       = 2
+	payloadBatchSize     = 3
+	payloadBatchIndex    = 4
+	payloadBatchID       = 5
+)
+
+// Batch is a single decoded otpauth-migration:// payload. Large exports are
+// split over several batches sharing the same BatchID.
+type Batch struct {
+	Accounts   []model.Account
+	Version    int
+	BatchSize  int
+	BatchIndex int
+	BatchID    int32
+}
+
+// Parse decodes a single otpauth-migration://offline?data=... URI.
+func Parse(raw string) (*Batch, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid migration URI: %w", err)
+	}
+	if u.Scheme != scheme {
+		return nil, fmt.Errorf("invalid migration URI: unexpected scheme %q", u.Scheme)
+	}
+
+	data := u.Query().Get("data")
+	if data == "" {
+		return nil, fmt.Errorf("invalid migration URI: missing data")
+	}
+
+	// Exports use standard base64, but the padding is often lost when the
+	// URI is copied around, and some tools emit the URL-safe alphabet.
+	data = strings.TrimRight(data, "=")
+	payload, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil {
+		payload, err = base64.RawURLEncoding.DecodeString(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid migration data: %w", err)
+	}
+
+	return decodePayload(payload)
+}
+
+func decodePayload(payload []byte) (*Batch, error) {
+	fields, err := decodeFields(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid migration payload: %w", err)
+	}
+
+	batch := &Batch{}
+	for _, f := range fields {
+		switch {
+		case f.num == payloadOTPParameters && f.wire == wireBytes:
+			acc, err := decodeParameters(f.bytes)
+			if err != nil {
+				return nil, err
+			}
+			batch.Accounts = append(batch.Accounts, *acc)
+		case f.num == payloadVersion && f.wire == wireVarint:
+			batch.Version = int(f.varint)
+		case f.num == payloadBatchSize && f.wire == wireVarint:
+			batch.BatchSize = int(f.varint)
+		case f.num == payloadBatchIndex && f.wire == wireVarint:
+			batch.BatchIndex = int(f.varint)
+		case f.num == payloadBatchID && f.wire == wireVarint:
+			batch.BatchID = int32(f.varint)
+		}
+	}
+	return batch, nil
+}
+
+func decodeParameters(buf []byte) (*model.Account, error) {
+	fields, err := decodeFields(buf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid migration entry: %w", err)
+	}
+
+	var secret []byte
+	var name string
+	algo, digits, typ := algoUnspecified, digitsUnspecified, typeUnspecified
+	acc := &model.Account{Period: model.DefaultPeriod}
+
+	for _, f := range fields {
+		switch f.num {
+		case paramSecret:
+			secret = f.bytes
+		case paramName:
+			name = string(f.bytes)
+		case paramIssuer:
+			acc.Issuer = string(f.bytes)
+		case paramAlgorithm:
+			algo = int(f.varint)
+		case paramDigits:
+			digits = int(f.varint)
+		case paramType:
+			typ = int(f.varint)
+		case paramCounter:
+			acc.Counter = int64(f.varint)
+		}
+	}
+
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("migration entry %q has no secret", name)
+	}
+	acc.Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret)
+
+	// Names are usually "Issuer:account"; keep only the account part when
+	// the prefix repeats the issuer.
+	acc.Label = name
+	if prefix, rest, ok := strings.Cut(name, ":"); ok {
+		if acc.Issuer == "" {
+			acc.Issuer = strings.TrimSpace(prefix)
+		}
+		if strings.EqualFold(strings.TrimSpace(prefix), acc.Issuer) {
+			acc.Label = strings.TrimSpace(rest)
+		}
+	}
+
+	switch algo {
+	case algoUnspecified, algoSHA1:
+		acc.Algorithm = "sha1"
+	case algoSHA256:
+		acc.Algorithm = "sha256"
+	case algoSHA512:
+		acc.Algorithm = "sha512"
+	case algoMD5:
+		return nil, fmt.Errorf("migration entry %q uses MD5, which is not supported", name)
+	default:
+		return nil, fmt.Errorf("migration entry %q has unknown algorithm %d", name, algo)
+	}
+
+	switch digits {
+	case digitsUnspecified, digitsSix:
+		acc.Digits = 6
+	case digitsEight:
+		acc.Digits = 8
+	default:
+		return nil, fmt.Errorf("migration entry %q has unknown digit count %d", name, digits)
+	}
+
+	switch typ {
+	case typeUnspecified, typeTOTP:
+		acc.Type = model.TypeTOTP
+	case typeHOTP:
+		acc.Type = model.TypeHOTP
+	default:
+		return nil, fmt.Errorf("migration entry %q has unknown type %d", name, typ)
+	}
+
+	return acc, nil
+}
+
+type Provider struct{}
+
+func New() *Provider {
+	return &Provider{}
+}
+
+func init() {
+	provider.Register(New())
+}
+
+func (p *Provider) Name() string {
+	return "gauth-migration"
+}
+
+// SupportsEncryption is false: migration payloads are always plain.
+func (p *Provider) SupportsEncryption() bool {
+	return false
+}
+
+func (p *Provider) Detect(data []byte) bool {
+	return strings.HasPrefix(provider.FirstLine(data), scheme+"://")
+}
+
+// Import reads one otpauth-migration:// URI per line, as produced when each
+// QR code of a multi-batch export is scanned.
+func (p *Provider) Import(data []byte, password string) ([]model.Account, error) {
+	var accounts []model.Account
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		batch, err := Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		accounts = append(accounts, batch.Accounts...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
@@ -0,0 +1,162 @@
+package gmigration
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/leeineian/gauth/internal/model"
+)
+
+// Exported by Google Authenticator for otpauth://totp/Example:alice@google.com?secret=JBSWY3DPEHPK3PXP
+const sampleURI = "otpauth-migration://offline?data=CjEKCkhlbGxvId6tvu8SGEV4YW1wbGU6YWxpY2VAZ29vZ2xlLmNvbRoHRXhhbXBsZSABKAEwAhABGAEgACjr4JKQBg%3D%3D"
+
+func TestParseSample(t *testing.T) {
+	batch, err := Parse(sampleURI)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if batch.Version != 1 || batch.BatchSize != 1 || batch.BatchIndex != 0 {
+		t.Errorf("unexpected batch header: %+v", batch)
+	}
+	if len(batch.Accounts) != 1 {
+		t.Fatalf("expected 1 account, got %d", len(batch.Accounts))
+	}
+
+	acc := batch.Accounts[0]
+	if acc.Secret != "JBSWY3DPEHPK3PXP" || acc.Issuer != "Example" || acc.Label != "alice@google.com" {
+		t.Errorf("unexpected account: %+v", acc)
+	}
+	if acc.Type != model.TypeTOTP || acc.Digits != 6 || acc.Algorithm != "sha1" || acc.Period != 30 {
+		t.Errorf("unexpected parameters: %+v", acc)
+	}
+}
+
+func TestImportMultiBatch(t *testing.T) {
+	hotp := appendBytesField(nil, paramSecret, []byte("12345678901234567890"))
+	hotp = appendBytesField(hotp, paramName, []byte("ops@corp"))
+	hotp = appendBytesField(hotp, paramIssuer, []byte("Corp"))
+	hotp = appendVarintField(hotp, paramAlgorithm, algoSHA256)
+	hotp = appendVarintField(hotp, paramDigits, digitsEight)
+	hotp = appendVarintField(hotp, paramType, typeHOTP)
+	hotp = appendVarintField(hotp, paramCounter, 42)
+	hotp = appendBytesField(hotp, 99, []byte("unknown field"))
+
+	payload := appendBytesField(nil, payloadOTPParameters, hotp)
+	payload = appendVarintField(payload, payloadVersion, 1)
+	payload = appendVarintField(payload, payloadBatchSize, 2)
+	payload = appendVarintField(payload, payloadBatchIndex, 1)
+	second := "otpauth-migration://offline?data=" + url.QueryEscape(base64.StdEncoding.EncodeToString(payload))
+
+	data := []byte(sampleURI + "\n\n" + second + "\n")
+	if !New().Detect(data) {
+		t.Error("Detect() = false for migration URIs")
+	}
+
+	accounts, err := New().Import(data, "")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(accounts) != 2 {
+		t.Fatalf("expected 2 accounts, got %d", len(accounts))
+	}
+
+	acc := accounts[1]
+	if acc.Secret != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" || acc.Issuer != "Corp" || acc.Label != "ops@corp" {
+		t.Errorf("unexpected account: %+v", acc)
+	}
+	if acc.Type != model.TypeHOTP || acc.Digits != 8 || acc.Algorithm != "sha256" || acc.Counter != 42 {
+		t.Errorf("unexpected parameters: %+v", acc)
+	}
+}
+
+func TestParseRejectsMD5(t *testing.T) {
+	entry := appendBytesField(nil, paramSecret, []byte("12345678901234567890"))
+	entry = appendVarintField(entry, paramAlgorithm, algoMD5)
+	payload := appendBytesField(nil, payloadOTPParameters, entry)
+
+	if _, err := decodePayload(payload); err == nil {
+		t.Error("expected error for MD5 entry, got nil")
+	}
+}
+
+func TestFormatBatches(t *testing.T) {
+	accounts := make([]model.Account, 0, BatchSize*2+3)
+	for i := 0; i < cap(accounts); i++ {
+		accounts = append(accounts, model.Account{
+			Secret: "JBSWY3DPEHPK3PXP", Issuer: "Issuer", Label: fmt.Sprintf("user%d", i),
+			Type: model.TypeHOTP, Digits: 8, Algorithm: "sha512", Counter: int64(i),
+		})
+	}
+
+	uris, err := Format(accounts)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	if len(uris) != 3 {
+		t.Fatalf("expected 3 batches, got %d", len(uris))
+	}
+
+	var decoded []model.Account
+	var batchID int32
+	for i, uri := range uris {
+		batch, err := Parse(uri)
+		if err != nil {
+			t.Fatalf("Parse(batch %d) error = %v", i, err)
+		}
+		if batch.BatchSize != 3 || batch.BatchIndex != i {
+			t.Errorf("batch %d: unexpected header %+v", i, batch)
+		}
+		if i == 0 {
+			batchID = batch.BatchID
+		} else if batch.BatchID != batchID {
+			t.Errorf("batch %d: batch ID %d differs from %d", i, batch.BatchID, batchID)
+		}
+		decoded = append(decoded, batch.Accounts...)
+	}
+
+	if len(decoded) != len(accounts) {
+		t.Fatalf("expected %d accounts, got %d", len(accounts), len(decoded))
+	}
+	for i, got := range decoded {
+		want := accounts[i]
+		if got.Secret != want.Secret || got.Issuer != want.Issuer || got.Label != want.Label ||
+			got.Type != want.Type || got.Digits != want.Digits || got.Algorithm != want.Algorithm ||
+			got.Counter != want.Counter {
+			t.Errorf("account %d: got %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestFormatRejectsUnsupported(t *testing.T) {
+	for _, acc := range []model.Account{
+		{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Steam", Label: "gamer", Type: model.TypeSteam, Digits: 5},
+		{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Odd", Label: "user", Type: model.TypeTOTP, Digits: 7},
+		{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Slow", Label: "user", Type: model.TypeTOTP, Digits: 6, Period: 60},
+	} {
+		if _, err := Format([]model.Account{acc}); err == nil {
+			t.Errorf("Format(%s) expected error, got nil", acc.FullIdentifier())
+		}
+	}
+}
+
+// Older vaults store types upper-case and export doesn't normalize them.
+func TestFormatUpperCaseTypes(t *testing.T) {
+	accounts := []model.Account{
+		{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Issuer", Label: "totp", Type: "TOTP", Digits: 6, Algorithm: "sha1", Period: 30},
+		{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Issuer", Label: "hotp", Type: "HOTP", Digits: 6, Algorithm: "sha1", Counter: 9},
+	}
+	uris, err := Format(accounts)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	batch, err := Parse(uris[0])
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := batch.Accounts; len(got) != 2 || got[0].Type != model.TypeTOTP || got[1].Type != model.TypeHOTP || got[1].Counter != 9 {
+		t.Errorf("round trip got %+v", got)
+	}
+}
@@ -0,0 +1,71 @@
+package gmigration
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Protobuf wire types used by the migration payload.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// field is a single decoded protobuf field. Only one of varint and bytes is
+// meaningful, depending on wire.
+type field struct {
+	num    int
+	wire   int
+	varint uint64
+	bytes  []byte
+}
+
+// decodeFields splits a protobuf message into its top-level fields. Unknown
+// fields are returned too and simply ignored by the callers, which keeps the
+// decoder forward compatible with newer exports.
+func decodeFields(buf []byte) ([]field, error) {
+	var fields []field
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return nil, fmt.Errorf("malformed field key")
+		}
+		buf = buf[n:]
+
+		f := field{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case wireVarint:
+			v, n := binary.Uvarint(buf)
+			if n <= 0 {
+				return nil, fmt.Errorf("malformed varint in field %d", f.num)
+			}
+			f.varint = v
+			buf = buf[n:]
+		case wireFixed64:
+			if len(buf) < 8 {
+				return nil, fmt.Errorf("truncated field %d", f.num)
+			}
+			f.varint = binary.LittleEndian.Uint64(buf)
+			buf = buf[8:]
+		case wireBytes:
+			l, n := binary.Uvarint(buf)
+			if n <= 0 || uint64(len(buf)-n) < l {
+				return nil, fmt.Errorf("truncated field %d", f.num)
+			}
+			f.bytes = buf[n : n+int(l)]
+			buf = buf[n+int(l):]
+		case wireFixed32:
+			if len(buf) < 4 {
+				return nil, fmt.Errorf("truncated field %d", f.num)
+			}
+			f.varint = uint64(binary.LittleEndian.Uint32(buf))
+			buf = buf[4:]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d in field %d", f.wire, f.num)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
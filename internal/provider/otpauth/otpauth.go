@@ -0,0 +1,198 @@
+package otpauth
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/provider"
+)
+
+const scheme = "otpauth"
+
+// Parse decodes a single otpauth:// Key URI into an account.
+func Parse(raw string) (*model.Account, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid otpauth URI: %w", err)
+	}
+	if u.Scheme != scheme {
+		return nil, fmt.Errorf("invalid otpauth URI: unexpected scheme %q", u.Scheme)
+	}
+
+	q := u.Query()
+
+	acc := &model.Account{
+		Secret:    strings.ToUpper(q.Get("secret")),
+		Digits:    model.DefaultDigits,
+		Algorithm: model.DefaultAlgo,
+		Period:    model.DefaultPeriod,
+		Counter:   model.DefaultCounter,
+	}
+	if acc.Secret == "" {
+		return nil, fmt.Errorf("invalid otpauth URI: missing secret")
+	}
+
+	switch strings.ToLower(u.Host) {
+	case "totp":
+		acc.Type = model.TypeTOTP
+	case "hotp":
+		acc.Type = model.TypeHOTP
+	case "steam":
+		acc.Type = model.TypeSteam
+	default:
+		return nil, fmt.Errorf("invalid otpauth URI: unsupported type %q", u.Host)
+	}
+	if strings.EqualFold(q.Get("encoder"), "steam") {
+		acc.Type = model.TypeSteam
+	}
+
+	// The issuer separator may be a literal or an escaped colon, so split
+	// the unescaped label. An issuer parameter that prefixes it is stripped
+	// whole, which keeps issuers that contain a colon intact.
+	label, err := url.PathUnescape(strings.TrimPrefix(u.EscapedPath(), "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid otpauth label: %w", err)
+	}
+	issuer := q.Get("issuer")
+	if rest, ok := strings.CutPrefix(label, issuer+":"); issuer != "" && ok {
+		label = rest
+	} else if i := strings.Index(label, ":"); i >= 0 {
+		acc.Issuer, label = label[:i], label[i+1:]
+	}
+	acc.Label = strings.TrimSpace(label)
+	acc.Issuer = strings.TrimSpace(acc.Issuer)
+	if issuer != "" {
+		acc.Issuer = issuer
+	}
+
+	if algo := q.Get("algorithm"); algo != "" {
+		acc.Algorithm = strings.ToLower(algo)
+	}
+	if v := q.Get("digits"); v != "" {
+		if acc.Digits, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid otpauth digits %q", v)
+		}
+	}
+	if v := q.Get("period"); v != "" {
+		if acc.Period, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid otpauth period %q", v)
+		}
+	}
+	if v := q.Get("counter"); v != "" {
+		if acc.Counter, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid otpauth counter %q", v)
+		}
+	}
+	if acc.Type.IsSteam() {
+		acc.Digits = model.SteamDigits
+	}
+
+	return acc, nil
+}
+
+// Format encodes an account as an otpauth:// Key URI.
+func Format(acc *model.Account) string {
+	typ := strings.ToLower(string(acc.Type))
+	if typ == "" {
+		typ = string(model.DefaultType)
+	}
+
+	label := escape(acc.DisplayLabel())
+	if acc.Issuer != "" {
+		label = escape(acc.Issuer) + ":" + label
+	}
+
+	params := []string{"secret=" + escape(strings.ToUpper(acc.Secret))}
+	if acc.Issuer != "" {
+		params = append(params, "issuer="+escape(acc.Issuer))
+	}
+	if acc.Algorithm != "" {
+		params = append(params, "algorithm="+strings.ToUpper(acc.Algorithm))
+	}
+	if acc.Digits != 0 {
+		params = append(params, "digits="+strconv.Itoa(acc.Digits))
+	}
+	if acc.Type.IsHOTP() {
+		params = append(params, "counter="+strconv.FormatInt(acc.Counter, 10))
+	} else if acc.Period != 0 {
+		params = append(params, "period="+strconv.FormatInt(acc.Period, 10))
+	}
+
+	return fmt.Sprintf("%s://%s/%s?%s", scheme, typ, label, strings.Join(params, "&"))
+}
+
+// escape percent-encodes s for use in a Key URI. Colons are escaped too so
+// they can't be confused with the issuer separator, and spaces become %20
+// as the Key URI format requires.
+func escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+type Provider struct{}
+
+func New() *Provider {
+	return &Provider{}
+}
+
+func init() {
+	provider.Register(New())
+}
+
+func (p *Provider) Name() string {
+	return "otpauth"
+}
+
+// SupportsEncryption is false: URI lists are always plain text.
+func (p *Provider) SupportsEncryption() bool {
+	return false
+}
+
+func (p *Provider) Detect(data []byte) bool {
+	return strings.HasPrefix(provider.FirstLine(data), scheme+"://")
+}
+
+// Import reads one otpauth:// URI per line. Blank lines and lines starting
+// with '#' are ignored.
+func (p *Provider) Import(data []byte, password string) ([]model.Account, error) {
+	var accounts []model.Account
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		acc, err := Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		accounts = append(accounts, *acc)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// Export writes one otpauth:// URI per line.
+func (p *Provider) Export(accounts []model.Account, password string) ([]byte, error) {
+	if password != "" {
+		return nil, fmt.Errorf("otpauth URI lists cannot be encrypted")
+	}
+
+	var buf bytes.Buffer
+	for _, a := range accounts {
+		buf.WriteString(Format(&a))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,121 @@
+package otpauth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leeineian/gauth/internal/model"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want model.Account
+	}{
+		{
+			uri: "otpauth://totp/Example:alice@google.com?secret=JBSWY3DPEHPK3PXP&issuer=Example",
+			want: model.Account{
+				Secret: "JBSWY3DPEHPK3PXP", Issuer: "Example", Label: "alice@google.com",
+				Type: model.TypeTOTP, Digits: 6, Algorithm: "sha1", Period: 30,
+			},
+		},
+		{
+			uri: "otpauth://totp/ACME%20Co:john.doe%40email.com?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ&issuer=ACME%20Co&algorithm=SHA256&digits=7&period=60",
+			want: model.Account{
+				Secret: "HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ", Issuer: "ACME Co", Label: "john.doe@email.com",
+				Type: model.TypeTOTP, Digits: 7, Algorithm: "sha256", Period: 60,
+			},
+		},
+		{
+			uri: "otpauth://hotp/Corp:ops:deploy?secret=jbswy3dpehpk3pxp&counter=42",
+			want: model.Account{
+				Secret: "JBSWY3DPEHPK3PXP", Issuer: "Corp", Label: "ops:deploy",
+				Type: model.TypeHOTP, Digits: 6, Algorithm: "sha1", Period: 30, Counter: 42,
+			},
+		},
+		{
+			uri: "otpauth://totp/GitHub%3Aoctocat?secret=JBSWY3DPEHPK3PXP",
+			want: model.Account{
+				Secret: "JBSWY3DPEHPK3PXP", Issuer: "GitHub", Label: "octocat",
+				Type: model.TypeTOTP, Digits: 6, Algorithm: "sha1", Period: 30,
+			},
+		},
+		{
+			uri: "otpauth://totp/We%3AIrd:user?secret=JBSWY3DPEHPK3PXP",
+			want: model.Account{
+				Secret: "JBSWY3DPEHPK3PXP", Issuer: "We", Label: "Ird:user",
+				Type: model.TypeTOTP, Digits: 6, Algorithm: "sha1", Period: 30,
+			},
+		},
+		{
+			uri: "otpauth://totp/We%3AIrd:user?secret=JBSWY3DPEHPK3PXP&issuer=We%3AIrd",
+			want: model.Account{
+				Secret: "JBSWY3DPEHPK3PXP", Issuer: "We:Ird", Label: "user",
+				Type: model.TypeTOTP, Digits: 6, Algorithm: "sha1", Period: 30,
+			},
+		},
+		{
+			uri: "otpauth://totp/Steam:gamer?secret=JBSWY3DPEHPK3PXP&issuer=Steam&encoder=steam",
+			want: model.Account{
+				Secret: "JBSWY3DPEHPK3PXP", Issuer: "Steam", Label: "gamer",
+				Type: model.TypeSteam, Digits: 5, Algorithm: "sha1", Period: 30,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.uri)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.uri, err)
+		}
+		if got.Secret != tt.want.Secret || got.Issuer != tt.want.Issuer || got.Label != tt.want.Label ||
+			got.Type != tt.want.Type || got.Digits != tt.want.Digits || got.Algorithm != tt.want.Algorithm ||
+			got.Period != tt.want.Period || got.Counter != tt.want.Counter {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.uri, *got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, uri := range []string{
+		"https://example.com/?secret=ABC",
+		"otpauth://totp/Example:alice",
+		"otpauth://motp/Example:alice?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&digits=six",
+	} {
+		if _, err := Parse(uri); err == nil {
+			t.Errorf("Parse(%q) expected error, got nil", uri)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	accounts := []model.Account{
+		{Secret: "JBSWY3DPEHPK3PXP", Issuer: "ACME Co", Label: "a:b c@d", Type: model.TypeTOTP, Digits: 8, Algorithm: "sha512", Period: 60},
+		{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Odd:Issuer", Label: "user", Type: model.TypeHOTP, Digits: 6, Algorithm: "sha1", Period: 30, Counter: 7},
+	}
+
+	for _, acc := range accounts {
+		uri := Format(&acc)
+		got, err := Parse(uri)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", uri, err)
+		}
+		if got.Issuer != acc.Issuer || got.Label != acc.Label || got.Type != acc.Type ||
+			got.Digits != acc.Digits || got.Algorithm != acc.Algorithm || got.Counter != acc.Counter {
+			t.Errorf("round trip of %q = %+v, want %+v", uri, *got, acc)
+		}
+		if acc.Type != model.TypeHOTP && got.Period != acc.Period {
+			t.Errorf("round trip of %q lost period: got %d, want %d", uri, got.Period, acc.Period)
+		}
+	}
+}
+
+// Older vaults store types upper-case and export doesn't normalize them.
+func TestFormatUpperCaseHOTP(t *testing.T) {
+	acc := model.Account{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Bank", Label: "me", Type: "HOTP", Digits: 6, Algorithm: "sha1", Period: 30, Counter: 7}
+	uri := Format(&acc)
+	if !strings.Contains(uri, "counter=7") || strings.Contains(uri, "period=") {
+		t.Errorf("Format() = %q, want a counter and no period", uri)
+	}
+}
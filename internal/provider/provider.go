@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/leeineian/gauth/internal/model"
+)
+
+// ErrPasswordRequired is returned by Import when the data is encrypted and
+// no password was given, so callers can prompt for one and retry.
+var ErrPasswordRequired = errors.New("backup is encrypted, please provide a password")
+
+// Provider reads and writes one backup format.
+type Provider interface {
+	// Name is the identifier used with --format.
+	Name() string
+	// Detect reports whether data looks like this format. It must be cheap
+	// and must not need a password.
+	Detect(data []byte) bool
+	Import(data []byte, password string) ([]model.Account, error)
+	Export(accounts []model.Account, password string) ([]byte, error)
+	// SupportsEncryption reports whether Import and Export accept a password.
+	SupportsEncryption() bool
+}
+
+// QRCodeExporter is implemented by formats meant to be scanned off the
+// screen. Each payload of an export is rendered as its own QR code.
+type QRCodeExporter interface {
+	QRPayloads(exported []byte) []string
+}
+
+var (
+	mu        sync.RWMutex
+	providers []Provider
+)
+
+// Register makes a provider available by name and for detection. Providers
+// register themselves from init, so detection follows registration order.
+func Register(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, existing := range providers {
+		if existing.Name() == p.Name() {
+			panic("provider: Register called twice for " + p.Name())
+		}
+	}
+	providers = append(providers, p)
+}
+
+// Get returns the provider registered under name.
+func Get(name string) (Provider, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, p := range providers {
+		if strings.EqualFold(p.Name(), name) {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown format: %s (expected one of %s)", name, strings.Join(names(), ", "))
+}
+
+// Detect returns the first provider that recognizes data.
+func Detect(data []byte) (Provider, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, p := range providers {
+		if p.Detect(data) {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("could not detect backup format, use --format (one of %s)", strings.Join(names(), ", "))
+}
+
+// Names lists the registered format names in alphabetical order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return names()
+}
+
+func names() []string {
+	out := make([]string, 0, len(providers))
+	for _, p := range providers {
+		out = append(out, p.Name())
+	}
+	sort.Strings(out)
+	return out
+}
+
+// NewUUID returns a random RFC 4122 version 4 UUID, for formats that key
+// entries or groups by UUID.
+func NewUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// FirstLine returns the first non-blank line of data that isn't a '#'
+// comment, which line-based formats use for detection.
+func FirstLine(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
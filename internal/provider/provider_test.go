@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leeineian/gauth/internal/model"
+)
+
+type fakeProvider struct {
+	name   string
+	prefix string
+}
+
+func (f *fakeProvider) Name() string             { return f.name }
+func (f *fakeProvider) SupportsEncryption() bool { return false }
+func (f *fakeProvider) Detect(data []byte) bool  { return strings.HasPrefix(FirstLine(data), f.prefix) }
+func (f *fakeProvider) Import(data []byte, password string) ([]model.Account, error) {
+	return nil, nil
+}
+func (f *fakeProvider) Export(accounts []model.Account, password string) ([]byte, error) {
+	return nil, nil
+}
+
+func TestRegistry(t *testing.T) {
+	Register(&fakeProvider{name: "beta", prefix: "beta://"})
+	Register(&fakeProvider{name: "alpha", prefix: "alpha://"})
+
+	if got := strings.Join(Names(), ","); got != "alpha,beta" {
+		t.Errorf("Names() = %s, want alpha,beta", got)
+	}
+
+	p, err := Get("ALPHA")
+	if err != nil || p.Name() != "alpha" {
+		t.Errorf("Get(ALPHA) = %v, %v", p, err)
+	}
+	if _, err := Get("gamma"); err == nil {
+		t.Error("expected error for unknown format, got nil")
+	}
+
+	p, err = Detect([]byte("# exported\n\nbeta://x\n"))
+	if err != nil || p.Name() != "beta" {
+		t.Errorf("Detect() = %v, %v", p, err)
+	}
+	if _, err := Detect([]byte("nothing here")); err == nil {
+		t.Error("expected error for unknown data, got nil")
+	}
+}
@@ -0,0 +1,335 @@
+package twofas
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/provider"
+)
+
+const (
+	schemaVersion = 4
+
+	// 2FAS derives backup keys with PBKDF2-HMAC-SHA256
+	kdfIterations = 10000
+	keyLen        = 32
+	saltLen       = 256
+	nonceLen      = 12
+
+	// reference is the fixed plaintext 2FAS encrypts next to the services so
+	// the app can check a password before decrypting anything else.
+	reference = "tRViSsLKzd86Hprh4ceC2OP7xazn4rrt4xhfEUbOjxLX8Rc3mkISXE0lWbmnWfggogbBJhtYgpK6fMl1D6mtsy92R3HkdGfwuXbzLebqVFJsR7IZ2w58t938iymwG4824igYy1wi6n2WDpO1Q1P69zwJGs2F5a1qP4MyIiDSD7NCV2OvidXQCBnDlGfmz0f1BQySRkkt4ryiJeCjD2o4QsveJ9uDBUn8ELyOrESv5R5DMDkD4iAF8TXU7KyoJujd"
+)
+
+type backup struct {
+	Services          []service `json:"services"`
+	Groups            []group   `json:"groups"`
+	UpdatedAt         int64     `json:"updatedAt"`
+	SchemaVersion     int       `json:"schemaVersion"`
+	AppVersionCode    int       `json:"appVersionCode,omitempty"`
+	AppVersionName    string    `json:"appVersionName,omitempty"`
+	AppOrigin         string    `json:"appOrigin,omitempty"`
+	ServicesEncrypted string    `json:"servicesEncrypted,omitempty"`
+	Reference         string    `json:"reference,omitempty"`
+}
+
+type group struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	IsExpanded bool   `json:"isExpanded"`
+	UpdatedAt  int64  `json:"updatedAt,omitempty"`
+}
+
+type service struct {
+	Name      string          `json:"name"`
+	Secret    string          `json:"secret"`
+	UpdatedAt int64           `json:"updatedAt,omitempty"`
+	OTP       otp             `json:"otp"`
+	Order     order           `json:"order"`
+	Icon      json.RawMessage `json:"icon,omitempty"`
+	GroupID   *string         `json:"groupId,omitempty"`
+}
+
+type otp struct {
+	Label     string `json:"label,omitempty"`
+	Account   string `json:"account,omitempty"`
+	Issuer    string `json:"issuer,omitempty"`
+	Digits    int    `json:"digits,omitempty"`
+	Period    int64  `json:"period,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Counter   int64  `json:"counter,omitempty"`
+	TokenType string `json:"tokenType,omitempty"`
+	Source    string `json:"source,omitempty"`
+}
+
+type order struct {
+	Position int `json:"position"`
+}
+
+type Provider struct{}
+
+func New() *Provider {
+	return &Provider{}
+}
+
+func init() {
+	provider.Register(New())
+}
+
+func (p *Provider) Name() string {
+	return "2fas"
+}
+
+func (p *Provider) SupportsEncryption() bool {
+	return true
+}
+
+func (p *Provider) Detect(data []byte) bool {
+	var b map[string]json.RawMessage
+	if err := json.Unmarshal(data, &b); err != nil {
+		return false
+	}
+	_, hasSchema := b["schemaVersion"]
+	_, hasServices := b["services"]
+	_, hasEncrypted := b["servicesEncrypted"]
+	return hasSchema && (hasServices || hasEncrypted)
+}
+
+func (p *Provider) Import(data []byte, password string) ([]model.Account, error) {
+	var b backup
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("invalid 2FAS backup: %w", err)
+	}
+
+	services := b.Services
+	if b.ServicesEncrypted != "" {
+		if password == "" {
+			return nil, provider.ErrPasswordRequired
+		}
+		plain, err := decrypt(b.ServicesEncrypted, password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt 2FAS backup (wrong password?): %w", err)
+		}
+		if err := json.Unmarshal(plain, &services); err != nil {
+			return nil, fmt.Errorf("invalid 2FAS services: %w", err)
+		}
+	}
+
+	groupNames := make(map[string]string, len(b.Groups))
+	for _, g := range b.Groups {
+		groupNames[g.ID] = g.Name
+	}
+
+	accounts := make([]model.Account, 0, len(services))
+	for _, s := range services {
+		accounts = append(accounts, *s.toAccount(groupNames))
+	}
+	return accounts, nil
+}
+
+// toAccount converts a service. Token types gauth can't generate are kept
+// as they are so the import preview lists them as invalid instead of
+// failing the whole backup.
+func (s *service) toAccount(groupNames map[string]string) *model.Account {
+	acc := &model.Account{
+		Secret:    s.Secret,
+		Issuer:    s.OTP.Issuer,
+		Label:     s.OTP.Account,
+		Digits:    s.OTP.Digits,
+		Algorithm: strings.ToLower(s.OTP.Algorithm),
+		Period:    s.OTP.Period,
+		Misc: map[string]interface{}{
+			"position": s.Order.Position,
+		},
+	}
+	if acc.Issuer == "" {
+		acc.Issuer = s.Name
+	}
+	if acc.Label == "" {
+		acc.Label = s.OTP.Label
+	}
+
+	switch strings.ToUpper(s.OTP.TokenType) {
+	case "", "TOTP":
+		acc.Type = model.TypeTOTP
+	case "HOTP":
+		acc.Type = model.TypeHOTP
+		acc.Counter = s.OTP.Counter
+	case "STEAM":
+		acc.Type = model.TypeSteam
+	default:
+		acc.Type = model.OTPType(strings.ToLower(s.OTP.TokenType))
+	}
+
+	if s.Name != "" && s.Name != acc.Issuer {
+		acc.Misc["name"] = s.Name
+	}
+	if s.GroupID != nil {
+		if name, ok := groupNames[*s.GroupID]; ok {
+			acc.Misc["groups"] = []interface{}{name}
+		}
+	}
+	if len(s.Icon) > 0 && string(s.Icon) != "null" {
+		var icon interface{}
+		if err := json.Unmarshal(s.Icon, &icon); err == nil {
+			acc.Misc["2fas_icon"] = icon
+		}
+	}
+
+	return acc
+}
+
+func (p *Provider) Export(accounts []model.Account, password string) ([]byte, error) {
+	now := time.Now().UnixMilli()
+	b := backup{
+		Services:      make([]service, 0, len(accounts)),
+		UpdatedAt:     now,
+		SchemaVersion: schemaVersion,
+	}
+	groupIDs := make(map[string]string)
+
+	for i, a := range accounts {
+		s := service{
+			Name:      a.Issuer,
+			Secret:    a.Secret,
+			UpdatedAt: now,
+			OTP: otp{
+				Label:     a.DisplayLabel(),
+				Account:   a.DisplayLabel(),
+				Issuer:    a.Issuer,
+				Digits:    a.Digits,
+				Period:    a.Period,
+				Algorithm: strings.ToUpper(a.Algorithm),
+				TokenType: strings.ToUpper(string(a.Type)),
+				Source:    "Manual",
+			},
+			Order: order{Position: i},
+		}
+		if name, ok := a.Misc["name"].(string); ok && name != "" {
+			s.Name = name
+		}
+		if s.OTP.TokenType == "" {
+			s.OTP.TokenType = strings.ToUpper(string(model.DefaultType))
+		}
+		if a.Type.IsHOTP() {
+			s.OTP.Counter = a.Counter
+			s.OTP.Period = 0
+		}
+		if pos, ok := a.MiscInt("position"); ok {
+			s.Order.Position = int(pos)
+		}
+		if icon, ok := a.Misc["2fas_icon"]; ok {
+			if raw, err := json.Marshal(icon); err == nil {
+				s.Icon = raw
+			}
+		}
+
+		// 2FAS services belong to at most one group
+		if names := a.MiscStrings("groups"); len(names) > 0 {
+			id, ok := groupIDs[names[0]]
+			if !ok {
+				id = provider.NewUUID()
+				groupIDs[names[0]] = id
+				b.Groups = append(b.Groups, group{ID: id, Name: names[0], IsExpanded: true, UpdatedAt: now})
+			}
+			s.GroupID = &id
+		}
+
+		b.Services = append(b.Services, s)
+	}
+
+	if password != "" {
+		plain, err := json.Marshal(b.Services)
+		if err != nil {
+			return nil, err
+		}
+
+		salt := make([]byte, saltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		key, err := pbkdf2.Key(sha256.New, password, salt, kdfIterations, keyLen)
+		if err != nil {
+			return nil, err
+		}
+
+		if b.ServicesEncrypted, err = encrypt(key, salt, plain); err != nil {
+			return nil, err
+		}
+		if b.Reference, err = encrypt(key, salt, []byte(reference)); err != nil {
+			return nil, err
+		}
+		b.Services = []service{}
+	}
+
+	return json.MarshalIndent(b, "", "  ")
+}
+
+// decrypt opens a "ciphertext:salt:iv" field, each part base64 encoded and
+// the GCM tag appended to the ciphertext.
+func decrypt(field string, password string) ([]byte, error) {
+	parts := strings.Split(field, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed encrypted field")
+	}
+
+	var decoded [3][]byte
+	for i, part := range parts {
+		b, err := base64.StdEncoding.DecodeString(part)
+		if err != nil {
+			return nil, fmt.Errorf("malformed encrypted field: %w", err)
+		}
+		decoded[i] = b
+	}
+	cipherText, salt, nonce := decoded[0], decoded[1], decoded[2]
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, kdfIterations, keyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(nonce))
+	}
+	return gcm.Open(nil, nonce, cipherText, nil)
+}
+
+func encrypt(key, salt, plain []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, nonceLen)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	cipherText := gcm.Seal(nil, nonce, plain, nil)
+	return strings.Join([]string{
+		base64.StdEncoding.EncodeToString(cipherText),
+		base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(nonce),
+	}, ":"), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,148 @@
+package twofas
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/provider"
+	importer "github.com/leeineian/gauth/internal/service"
+)
+
+const sampleBackup = `{
+  "services": [
+    {
+      "name": "GitHub",
+      "secret": "JBSWY3DPEHPK3PXP",
+      "updatedAt": 1700000000000,
+      "otp": {"label": "octocat", "account": "octocat", "issuer": "GitHub", "digits": 6, "period": 30, "algorithm": "SHA1", "tokenType": "TOTP", "source": "Link"},
+      "order": {"position": 1},
+      "icon": {"selected": "Label", "label": {"text": "GI", "backgroundColor": "Orange"}},
+      "groupId": "a1b2"
+    },
+    {
+      "name": "Corp VPN",
+      "secret": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+      "otp": {"account": "ops", "digits": 8, "algorithm": "SHA256", "counter": 42, "tokenType": "HOTP"},
+      "order": {"position": 0}
+    }
+  ],
+  "groups": [{"id": "a1b2", "name": "Work", "isExpanded": true}],
+  "updatedAt": 1700000000000,
+  "schemaVersion": 4,
+  "appOrigin": "android"
+}`
+
+func TestImportPlain(t *testing.T) {
+	accounts, err := New().Import([]byte(sampleBackup), "")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(accounts) != 2 {
+		t.Fatalf("expected 2 accounts, got %d", len(accounts))
+	}
+
+	gh := accounts[0]
+	if gh.Issuer != "GitHub" || gh.Label != "octocat" || gh.Type != model.TypeTOTP || gh.Digits != 6 || gh.Period != 30 {
+		t.Errorf("unexpected account: %+v", gh)
+	}
+	if got := gh.MiscStrings("groups"); !reflect.DeepEqual(got, []string{"Work"}) {
+		t.Errorf("expected groups [Work], got %v", got)
+	}
+	if pos, _ := gh.MiscInt("position"); pos != 1 {
+		t.Errorf("expected position 1, got %d", pos)
+	}
+
+	vpn := accounts[1]
+	if vpn.Issuer != "Corp VPN" || vpn.Label != "ops" || vpn.Type != model.TypeHOTP ||
+		vpn.Digits != 8 || vpn.Algorithm != "sha256" || vpn.Counter != 42 {
+		t.Errorf("unexpected account: %+v", vpn)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	p := New()
+	original, err := p.Import([]byte(sampleBackup), "")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	for _, password := range []string{"", "correct horse"} {
+		data, err := p.Export(original, password)
+		if err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+		if !p.Detect(data) {
+			t.Error("Detect() = false for exported backup")
+		}
+
+		accounts, err := p.Import(data, password)
+		if err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+		if !reflect.DeepEqual(accounts, original) {
+			t.Errorf("round trip (password %q) mismatch:\ngot  %+v\nwant %+v", password, accounts, original)
+		}
+
+		if password != "" {
+			if _, err := p.Import(data, "wrong"); err == nil {
+				t.Error("expected error with wrong password, got nil")
+			}
+			if _, err := p.Import(data, ""); !errors.Is(err, provider.ErrPasswordRequired) {
+				t.Errorf("expected ErrPasswordRequired without password, got %v", err)
+			}
+		}
+	}
+}
+
+// One service of a token type gauth can't generate must not block the
+// others.
+func TestImportUnsupportedTokenType(t *testing.T) {
+	backup := `{
+  "services": [
+    {"name": "GitHub", "secret": "JBSWY3DPEHPK3PXP", "otp": {"account": "octocat", "tokenType": "TOTP"}, "order": {"position": 0}},
+    {"name": "Bank", "secret": "JBSWY3DPEHPK3PXP", "otp": {"account": "me", "tokenType": "YANDEX"}, "order": {"position": 1}},
+    {"name": "Steam", "secret": "JBSWY3DPEHPK3PXP", "otp": {"account": "gamer", "tokenType": "STEAM"}, "order": {"position": 2}}
+  ],
+  "groups": [],
+  "schemaVersion": 4
+}`
+
+	accounts, err := New().Import([]byte(backup), "")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	plan := importer.PlanImport(nil, accounts)
+	want := []importer.ImportStatus{importer.ImportNew, importer.ImportInvalid, importer.ImportNew}
+	if len(plan) != len(want) {
+		t.Fatalf("expected %d plan entries, got %d", len(want), len(plan))
+	}
+	for i, e := range plan {
+		if e.Status != want[i] {
+			t.Errorf("%s: status %s, want %s (%s)", e.Account.FullIdentifier(), e.Status, want[i], e.Detail)
+		}
+	}
+	if !strings.Contains(plan[1].Detail, "yandex") {
+		t.Errorf("invalid entry should name its type: %q", plan[1].Detail)
+	}
+}
+
+// Older vaults store types upper-case and export doesn't normalize them.
+func TestExportUpperCaseHOTP(t *testing.T) {
+	acc := model.Account{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Bank", Label: "me", Type: "HOTP", Digits: 6, Algorithm: "sha1", Period: 30, Counter: 42}
+	p := New()
+	data, err := p.Export([]model.Account{acc}, "")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	accounts, err := p.Import(data, "")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if got := accounts[0]; got.Type != model.TypeHOTP || got.Counter != 42 || got.Period != 0 {
+		t.Errorf("round trip got %+v", got)
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/leeineian/gauth/internal/model"
+)
+
+// steamAlphabet is the 26-symbol alphabet used by Steam Guard codes.
+const steamAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+
+// DecodeSecret decodes a Base32 OTP secret, tolerating lowercase letters,
+// spaces, dashes and missing padding.
+func DecodeSecret(secret string) ([]byte, error) {
+	s := strings.ToUpper(secret)
+	s = strings.NewReplacer(" ", "", "-", "", "\t", "").Replace(s)
+	s = strings.TrimRight(s, "=")
+	if s == "" {
+		return nil, fmt.Errorf("secret is empty")
+	}
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Base32 secret: %w", err)
+	}
+	return key, nil
+}
+
+// hashFor maps an account algorithm name onto its hash constructor.
+func hashFor(algorithm string) (func() hash.Hash, error) {
+	switch strings.ToLower(strings.ReplaceAll(algorithm, "-", "")) {
+	case "", "sha1":
+		return sha1.New, nil
+	case "sha256":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
+	}
+}
+
+// truncate runs the HMAC and applies the RFC 4226 dynamic truncation,
+// returning the 31-bit binary code.
+func truncate(key []byte, counter uint64, algorithm string) (uint32, error) {
+	h, err := hashFor(algorithm)
+	if err != nil {
+		return 0, err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(h, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	return binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff, nil
+}
+
+// HOTP computes the RFC 4226 code for key at the given counter.
+func HOTP(key []byte, counter uint64, digits int, algorithm string) (string, error) {
+	// The dynamic truncation yields a 31-bit value, so more than
+	// model.MaxDigits digits would only ever be zero-padded.
+	if digits <= 0 || digits > model.MaxDigits {
+		return "", fmt.Errorf("unsupported digit count: %d", digits)
+	}
+
+	bin, err := truncate(key, counter, algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	mod := uint64(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, uint64(bin)%mod), nil
+}
+
+// TOTP computes the RFC 6238 code for key at unixTime using the given period.
+func TOTP(key []byte, unixTime, period int64, digits int, algorithm string) (string, error) {
+	if period <= 0 {
+		return "", fmt.Errorf("period must be positive")
+	}
+	if unixTime < 0 {
+		return "", fmt.Errorf("time must not be before the Unix epoch")
+	}
+	return HOTP(key, uint64(unixTime/period), digits, algorithm)
+}
+
+// Steam computes a Steam Guard code: a TOTP value rendered as five characters
+// of the Steam alphabet instead of decimal digits.
+func Steam(key []byte, unixTime, period int64) (string, error) {
+	if period <= 0 {
+		return "", fmt.Errorf("period must be positive")
+	}
+	if unixTime < 0 {
+		return "", fmt.Errorf("time must not be before the Unix epoch")
+	}
+
+	bin, err := truncate(key, uint64(unixTime/period), "sha1")
+	if err != nil {
+		return "", err
+	}
+
+	code := make([]byte, model.SteamDigits)
+	for i := range code {
+		code[i] = steamAlphabet[bin%uint32(len(steamAlphabet))]
+		bin /= uint32(len(steamAlphabet))
+	}
+	return string(code), nil
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/leeineian/gauth/internal/model"
+)
+
+// ImportStatus classifies an incoming account against the vault.
+type ImportStatus int
+
+const (
+	// ImportNew accounts have no counterpart in the vault.
+	ImportNew ImportStatus = iota
+	// ImportIdentical accounts match an existing one in secret and parameters.
+	ImportIdentical
+	// ImportConflict accounts share an identifier with an existing one but
+	// differ in secret or parameters.
+	ImportConflict
+	// ImportInvalid accounts fail validation and are never imported.
+	ImportInvalid
+)
+
+func (s ImportStatus) String() string {
+	switch s {
+	case ImportNew:
+		return "new"
+	case ImportIdentical:
+		return "identical"
+	case ImportConflict:
+		return "conflict"
+	case ImportInvalid:
+		return "invalid"
+	}
+	return "unknown"
+}
+
+// Resolution decides what happens to a conflicting account.
+type Resolution string
+
+const (
+	// ResolveKeep keeps the vault's account and drops the incoming one.
+	ResolveKeep Resolution = "keep"
+	// ResolveOverwrite replaces the vault's account with the incoming one.
+	ResolveOverwrite Resolution = "overwrite"
+	// ResolveRename adds the incoming account under a new label.
+	ResolveRename Resolution = "rename"
+	// ResolveKeepBoth adds the incoming account alongside the existing one,
+	// keeping its label and numbering its issuer, e.g. "GitLab (2)", so
+	// identifiers stay unique.
+	ResolveKeepBoth Resolution = "both"
+)
+
+// ParseResolution validates a --strategy value.
+func ParseResolution(s string) (Resolution, error) {
+	switch r := Resolution(strings.ToLower(s)); r {
+	case ResolveKeep, ResolveOverwrite, ResolveRename, ResolveKeepBoth:
+		return r, nil
+	}
+	return "", fmt.Errorf("unknown strategy: %s (expected keep, overwrite, rename or both)", s)
+}
+
+// ImportEntry is one incoming account and how it relates to the vault.
+type ImportEntry struct {
+	// Account is the normalized incoming account.
+	Account model.Account
+	// Fixes lists what normalization changed.
+	Fixes  []string
+	Status ImportStatus
+	// Existing is the index of the matching vault account for identical
+	// and conflicting entries, -1 otherwise.
+	Existing int
+	// Detail explains a conflict or why an entry is invalid.
+	Detail string
+}
+
+// ImportSummary counts what ApplyImport did.
+type ImportSummary struct {
+	Added       int
+	Overwritten int
+	Renamed     int
+	Skipped     int
+	Invalid     int
+}
+
+// PlanImport normalizes every incoming account and classifies it against
+// existing. Accounts repeated within incoming are compared against their
+// earlier copy. The incoming slice is left untouched.
+func PlanImport(existing, incoming []model.Account) []ImportEntry {
+	byID := make(map[string]int, len(existing))
+	for i, e := range existing {
+		byID[e.FullIdentifier()] = i
+	}
+	pending := make(map[string]model.Account)
+
+	plan := make([]ImportEntry, 0, len(incoming))
+	for _, acc := range incoming {
+		fixes := acc.Normalize()
+		entry := ImportEntry{Account: acc, Fixes: fixes, Status: ImportNew, Existing: -1}
+		id := acc.FullIdentifier()
+
+		if err := acc.Validate(); err != nil {
+			entry.Status = ImportInvalid
+			entry.Detail = err.Error()
+		} else if i, ok := byID[id]; ok {
+			entry.Existing = i
+			entry.Status, entry.Detail = compare(&existing[i], &acc)
+		} else if earlier, ok := pending[id]; ok {
+			entry.Status, entry.Detail = compare(&earlier, &acc)
+			if entry.Status == ImportConflict {
+				entry.Detail = "repeated in backup: " + entry.Detail
+			}
+		} else {
+			pending[id] = acc
+		}
+
+		plan = append(plan, entry)
+	}
+	return plan
+}
+
+// compare reports whether b is identical to a, ignoring the HOTP counter and
+// metadata, which legitimately drift between backups.
+func compare(a, b *model.Account) (ImportStatus, string) {
+	var diffs []string
+	if normalizeSecret(a.Secret) != normalizeSecret(b.Secret) {
+		diffs = append(diffs, "secret differs")
+	}
+	if !strings.EqualFold(string(a.Type), string(b.Type)) {
+		diffs = append(diffs, fmt.Sprintf("type %s → %s", a.Type, b.Type))
+	}
+	if a.Digits != b.Digits {
+		diffs = append(diffs, fmt.Sprintf("digits %d → %d", a.Digits, b.Digits))
+	}
+	if !strings.EqualFold(a.Algorithm, b.Algorithm) {
+		diffs = append(diffs, fmt.Sprintf("algorithm %s → %s", a.Algorithm, b.Algorithm))
+	}
+	if a.Type.IsTimeBased() && a.Period != b.Period {
+		diffs = append(diffs, fmt.Sprintf("period %ds → %ds", a.Period, b.Period))
+	}
+
+	if len(diffs) == 0 {
+		return ImportIdentical, ""
+	}
+	return ImportConflict, strings.Join(diffs, ", ")
+}
+
+func normalizeSecret(s string) string {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	return strings.TrimRight(s, "=")
+}
+
+// ResolveFunc decides a conflict. suggested is a free label for renames; a
+// rename returning an empty label uses it.
+type ResolveFunc func(entry ImportEntry, suggested string) (Resolution, string, error)
+
+// ApplyImport merges the plan into existing. New accounts are added,
+// identical and invalid ones skipped, and resolve decides each conflict.
+func ApplyImport(existing []model.Account, plan []ImportEntry, resolve ResolveFunc) ([]model.Account, ImportSummary, error) {
+	var summary ImportSummary
+	merged := append([]model.Account(nil), existing...)
+
+	// index tracks where each identifier lives in merged, so overwriting
+	// also works for accounts repeated within the backup itself.
+	index := make(map[string]int, len(merged))
+	for i, a := range merged {
+		index[a.FullIdentifier()] = i
+	}
+	add := func(acc model.Account) {
+		merged = append(merged, acc)
+		index[acc.FullIdentifier()] = len(merged) - 1
+	}
+	taken := func(id string) bool {
+		_, ok := index[id]
+		return ok
+	}
+
+	for _, entry := range plan {
+		switch entry.Status {
+		case ImportInvalid:
+			summary.Invalid++
+			continue
+		case ImportIdentical:
+			summary.Skipped++
+			continue
+		case ImportNew:
+			add(entry.Account)
+			summary.Added++
+			continue
+		}
+
+		acc := entry.Account
+		suggested := UniqueLabel(&acc, taken)
+		resolution, label, err := resolve(entry, suggested)
+		if err != nil {
+			return nil, summary, err
+		}
+
+		switch resolution {
+		case ResolveKeep:
+			summary.Skipped++
+		case ResolveOverwrite:
+			merged[index[acc.FullIdentifier()]] = acc
+			summary.Overwritten++
+		case ResolveRename:
+			if label == "" {
+				label = suggested
+			}
+			acc.Label = label
+			if taken(acc.FullIdentifier()) {
+				return nil, summary, fmt.Errorf("account already exists: %s", acc.FullIdentifier())
+			}
+			add(acc)
+			summary.Renamed++
+		case ResolveKeepBoth:
+			acc.Label = acc.DisplayLabel()
+			acc.Issuer = UniqueIssuer(&acc, taken)
+			add(acc)
+			summary.Renamed++
+		default:
+			return nil, summary, fmt.Errorf("unknown resolution: %s", resolution)
+		}
+	}
+
+	return merged, summary, nil
+}
+
+// UniqueIssuer suggests an issuer for acc, suffixed with a number, whose
+// identifier is not taken.
+func UniqueIssuer(acc *model.Account, taken func(id string) bool) string {
+	for n := 2; ; n++ {
+		candidate := *acc
+		candidate.Issuer = fmt.Sprintf("%s (%d)", acc.Issuer, n)
+		if !taken(candidate.FullIdentifier()) {
+			return candidate.Issuer
+		}
+	}
+}
+
+// UniqueLabel suggests a label for acc, suffixed with a number, whose
+// identifier is not taken.
+func UniqueLabel(acc *model.Account, taken func(id string) bool) string {
+	base := acc.DisplayLabel()
+	for n := 2; ; n++ {
+		candidate := *acc
+		candidate.Label = fmt.Sprintf("%s (%d)", base, n)
+		if !taken(candidate.FullIdentifier()) {
+			return candidate.Label
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/leeineian/gauth/internal/model"
+)
+
+func testAccount(issuer, label, secret string) model.Account {
+	return model.Account{
+		Issuer: issuer, Label: label, Secret: secret,
+		Type: model.TypeTOTP, Digits: 6, Algorithm: "sha1", Period: 30,
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	existing := []model.Account{
+		testAccount("GitHub", "octocat", "JBSWY3DPEHPK3PXP"),
+		testAccount("GitLab", "tanuki", "JBSWY3DPEHPK3PXP"),
+	}
+
+	rotated := testAccount("GitLab", "tanuki", "GEZDGNBVGY3TQOJQ")
+	invalid := testAccount("", "nobody", "JBSWY3DPEHPK3PXP")
+	identical := testAccount("GitHub", "octocat", "jbswy3dpehpk3pxp")
+	identical.Counter = 7 // counters drift and don't count as a difference
+
+	incoming := []model.Account{
+		identical,
+		rotated,
+		testAccount("Slack", "me", "JBSWY3DPEHPK3PXP"),
+		invalid,
+		testAccount("Slack", "me", "GEZDGNBVGY3TQOJQ"),
+	}
+
+	plan := PlanImport(existing, incoming)
+	want := []ImportStatus{ImportIdentical, ImportConflict, ImportNew, ImportInvalid, ImportConflict}
+	for i, e := range plan {
+		if e.Status != want[i] {
+			t.Errorf("entry %d (%s): status %s, want %s", i, e.Account.FullIdentifier(), e.Status, want[i])
+		}
+	}
+	if plan[1].Existing != 1 || plan[1].Detail != "secret differs" {
+		t.Errorf("unexpected conflict entry: %+v", plan[1])
+	}
+}
+
+func TestApplyImportStrategies(t *testing.T) {
+	existing := []model.Account{testAccount("GitLab", "tanuki", "JBSWY3DPEHPK3PXP")}
+	incoming := []model.Account{
+		testAccount("GitLab", "tanuki", "GEZDGNBVGY3TQOJQ"),
+		testAccount("Slack", "me", "JBSWY3DPEHPK3PXP"),
+	}
+	plan := PlanImport(existing, incoming)
+
+	tests := []struct {
+		resolution Resolution
+		labels     []string
+		secret     string
+	}{
+		{ResolveKeep, []string{"tanuki", "me"}, "JBSWY3DPEHPK3PXP"},
+		{ResolveOverwrite, []string{"tanuki", "me"}, "GEZDGNBVGY3TQOJQ"},
+		{ResolveRename, []string{"tanuki", "tanuki (2)", "me"}, "JBSWY3DPEHPK3PXP"},
+		{ResolveKeepBoth, []string{"tanuki", "tanuki", "me"}, "JBSWY3DPEHPK3PXP"},
+	}
+
+	for _, tt := range tests {
+		merged, _, err := ApplyImport(existing, plan, func(ImportEntry, string) (Resolution, string, error) {
+			return tt.resolution, "", nil
+		})
+		if err != nil {
+			t.Fatalf("%s: ApplyImport() error = %v", tt.resolution, err)
+		}
+		if len(merged) != len(tt.labels) {
+			t.Fatalf("%s: expected %d accounts, got %d", tt.resolution, len(tt.labels), len(merged))
+		}
+		for i, label := range tt.labels {
+			if merged[i].Label != label {
+				t.Errorf("%s: account %d label %q, want %q", tt.resolution, i, merged[i].Label, label)
+			}
+		}
+		if tt.resolution == ResolveKeepBoth && merged[1].Issuer != "GitLab (2)" {
+			t.Errorf("%s: imported issuer %q, want %q", tt.resolution, merged[1].Issuer, "GitLab (2)")
+		}
+		if merged[0].Secret != tt.secret {
+			t.Errorf("%s: existing secret %s, want %s", tt.resolution, merged[0].Secret, tt.secret)
+		}
+	}
+
+	if len(existing) != 1 || existing[0].Secret != "JBSWY3DPEHPK3PXP" {
+		t.Error("ApplyImport modified the existing slice")
+	}
+}
+
+// Keeping both must never leave two accounts with the same identifier,
+// which add and edit refuse, even when the backup repeats the account.
+func TestKeepBothStaysUnique(t *testing.T) {
+	existing := []model.Account{testAccount("GitLab", "tanuki", "JBSWY3DPEHPK3PXP")}
+	incoming := []model.Account{
+		testAccount("GitLab", "tanuki", "GEZDGNBVGY3TQOJQ"),
+		testAccount("GitLab", "tanuki", "MFRGGZDFMZTWQ2LK"),
+	}
+
+	merged, summary, err := ApplyImport(existing, PlanImport(existing, incoming), func(ImportEntry, string) (Resolution, string, error) {
+		return ResolveKeepBoth, "ignored", nil
+	})
+	if err != nil {
+		t.Fatalf("ApplyImport() error = %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, a := range merged {
+		if seen[a.FullIdentifier()] {
+			t.Errorf("duplicate identifier %s", a.FullIdentifier())
+		}
+		seen[a.FullIdentifier()] = true
+	}
+	if len(merged) != 3 || merged[1].FullIdentifier() != "GitLab (2):tanuki" || merged[2].FullIdentifier() != "GitLab (3):tanuki" || summary.Renamed != 2 {
+		t.Errorf("unexpected merge %+v, summary %+v", merged, summary)
+	}
+}
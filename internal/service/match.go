@@ -0,0 +1,79 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/leeineian/gauth/internal/model"
+)
+
+// Match returns the indexes of the accounts matching query, compared
+// case-insensitively against the issuer, the label and "issuer:label".
+//
+// With exact set only whole-field matches count. Otherwise the best tier
+// wins: whole-field matches, then substrings, then fuzzy subsequences
+// ("ghb" matches "GitHub"). An empty query matches every account.
+func Match(accounts []model.Account, query string, exact bool) []int {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	var tiers [3][]int
+	for i := range accounts {
+		tier := matchTier(&accounts[i], query)
+		if tier < 0 || (exact && tier > 0) {
+			continue
+		}
+		tiers[tier] = append(tiers[tier], i)
+	}
+
+	for _, t := range tiers {
+		if len(t) > 0 {
+			return t
+		}
+	}
+	return nil
+}
+
+// matchTier ranks how well acc matches query: 0 for a whole field, 1 for a
+// substring, 2 for a subsequence and -1 for no match.
+func matchTier(acc *model.Account, query string) int {
+	if query == "" {
+		return 0
+	}
+
+	fields := []string{
+		strings.ToLower(acc.Issuer),
+		strings.ToLower(acc.DisplayLabel()),
+		strings.ToLower(acc.FullIdentifier()),
+	}
+
+	best := -1
+	for _, f := range fields {
+		tier := -1
+		switch {
+		case f == query:
+			tier = 0
+		case strings.Contains(f, query):
+			tier = 1
+		case isSubsequence(query, f):
+			tier = 2
+		}
+		if tier >= 0 && (best < 0 || tier < best) {
+			best = tier
+		}
+	}
+	return best
+}
+
+// isSubsequence reports whether the runes of needle appear in haystack in
+// order, not necessarily adjacent.
+func isSubsequence(needle, haystack string) bool {
+	rest := []rune(needle)
+	for _, r := range haystack {
+		if len(rest) == 0 {
+			break
+		}
+		if r == rest[0] {
+			rest = rest[1:]
+		}
+	}
+	return len(rest) == 0
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/leeineian/gauth/internal/model"
+)
+
+func TestMatch(t *testing.T) {
+	accounts := []model.Account{
+		testAccount("GitHub", "octocat", "JBSWY3DPEHPK3PXP"),
+		testAccount("GitHub", "work", "JBSWY3DPEHPK3PXP"),
+		testAccount("GitLab", "tanuki", "JBSWY3DPEHPK3PXP"),
+		testAccount("Slack", "GitHub:bot", "JBSWY3DPEHPK3PXP"),
+	}
+
+	tests := []struct {
+		query string
+		exact bool
+		want  []int
+	}{
+		{"", false, []int{0, 1, 2, 3}},
+		{"github", false, []int{0, 1}},       // whole issuer beats the substring in Slack's label
+		{"git", false, []int{0, 1, 2, 3}},    // substrings
+		{"GitHub:work", false, []int{1}},     // full identifier
+		{"tnk", false, []int{2}},             // subsequence
+		{"octo", true, nil},                  // exact ignores partial matches
+		{"slack:github:bot", true, []int{3}}, // labels may contain colons
+		{"nothing", false, nil},
+	}
+	for _, tt := range tests {
+		if got := Match(accounts, tt.query, tt.exact); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Match(%q, exact=%v) = %v, want %v", tt.query, tt.exact, got, tt.want)
+		}
+	}
+}
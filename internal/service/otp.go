@@ -1,66 +1,117 @@
 package service
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
-	"github.com/grijul/otpgen"
 	"github.com/leeineian/gauth/internal/model"
 )
 
-type OTPService struct{}
+type OTPService struct {
+	now func() time.Time
+}
 
 func NewOTPService() *OTPService {
-	return &OTPService{}
+	return &OTPService{now: time.Now}
 }
 
 func (s *OTPService) Generate(acc *model.Account) (*model.OTPResult, error) {
-	if strings.ToLower(string(acc.Type)) == "hotp" {
+	switch model.OTPType(strings.ToLower(string(acc.Type))) {
+	case model.TypeHOTP:
 		return s.generateHOTP(acc)
+	case model.TypeSteam:
+		return s.generateSteam(acc)
 	}
 	return s.generateTOTP(acc)
 }
 
+func (s *OTPService) generateSteam(acc *model.Account) (*model.OTPResult, error) {
+	key, err := DecodeSecret(acc.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	// Steam ignores the stored period, older imports may carry another one
+	now := s.now().Unix()
+	code, err := Steam(key, now, model.SteamPeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	return timeBasedResult(code, now, model.SteamPeriod), nil
+}
+
 func (s *OTPService) generateTOTP(acc *model.Account) (*model.OTPResult, error) {
-	now := time.Now().Unix()
+	key, err := DecodeSecret(acc.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().Unix()
 	period := acc.Period
 	if period == 0 {
-		period = 30
+		period = model.DefaultPeriod
+	}
+
+	code, err := TOTP(key, now, period, digitsOf(acc), acc.Algorithm)
+	if err != nil {
+		return nil, err
 	}
 
-	t := &otpgen.TOTP{
-		Secret:    acc.Secret,
-		Digits:    acc.Digits,
-		Algorithm: acc.Algorithm,
-		Period:    period,
-		UnixTime:  now,
+	return timeBasedResult(code, now, period), nil
+}
+
+func (s *OTPService) generateHOTP(acc *model.Account) (*model.OTPResult, error) {
+	key, err := DecodeSecret(acc.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if acc.Counter < 0 {
+		return nil, fmt.Errorf("counter must not be negative")
 	}
 
-	code, err := t.Generate()
+	code, err := HOTP(key, uint64(acc.Counter), digitsOf(acc), acc.Algorithm)
 	if err != nil {
 		return nil, err
 	}
 
 	return &model.OTPResult{
 		Code:      code,
-		Remaining: period - (now % period),
+		Remaining: 0,
 	}, nil
 }
 
-func (s *OTPService) generateHOTP(acc *model.Account) (*model.OTPResult, error) {
-	h := &otpgen.HOTP{
-		Secret:  acc.Secret,
-		Digits:  acc.Digits,
-		Counter: acc.Counter,
+// Advance moves an HOTP account to its next counter value and returns the
+// code for the new counter. The caller is responsible for persisting acc.
+func (s *OTPService) Advance(acc *model.Account) (*model.OTPResult, error) {
+	if !acc.Type.IsHOTP() {
+		return nil, fmt.Errorf("%s is not an HOTP account", acc.FullIdentifier())
 	}
 
-	code, err := h.Generate()
+	acc.Counter++
+	res, err := s.generateHOTP(acc)
 	if err != nil {
+		acc.Counter--
 		return nil, err
 	}
+	return res, nil
+}
 
+func timeBasedResult(code string, now, period int64) *model.OTPResult {
+	start := now - now%period
 	return &model.OTPResult{
-		Code:      code,
-		Remaining: 0,
-	}, nil
+		Code:        code,
+		Remaining:   start + period - now,
+		PeriodStart: start,
+		PeriodEnd:   start + period,
+	}
+}
+
+func digitsOf(acc *model.Account) int {
+	if acc.Digits == 0 {
+		return model.DefaultDigits
+	}
+	return acc.Digits
 }
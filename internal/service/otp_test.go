@@ -0,0 +1,207 @@
+package service
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/leeineian/gauth/internal/model"
+)
+
+// RFC 4226 Appendix D
+func TestHOTPRFC4226(t *testing.T) {
+	key := []byte("12345678901234567890")
+	want := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+
+	for counter, expected := range want {
+		got, err := HOTP(key, uint64(counter), 6, "sha1")
+		if err != nil {
+			t.Fatalf("HOTP(%d) error = %v", counter, err)
+		}
+		if got != expected {
+			t.Errorf("HOTP(%d) = %s, want %s", counter, got, expected)
+		}
+	}
+}
+
+// The decimal values in RFC 4226 Appendix D cover every supported length.
+func TestHOTPDigitLengths(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		counter uint64
+		digits  int
+		want    string
+	}{
+		{0, 4, "5224"},
+		{0, 7, "4755224"},
+		{0, 10, "1284755224"},
+		{1, 9, "094287082"},
+		{1, 10, "1094287082"},
+	}
+
+	for _, tt := range tests {
+		got, err := HOTP(key, tt.counter, tt.digits, "sha1")
+		if err != nil {
+			t.Fatalf("HOTP(%d, %d digits) error = %v", tt.counter, tt.digits, err)
+		}
+		if got != tt.want {
+			t.Errorf("HOTP(%d, %d digits) = %s, want %s", tt.counter, tt.digits, got, tt.want)
+		}
+	}
+
+	if _, err := HOTP(key, 0, 11, "sha1"); err == nil {
+		t.Error("expected error for 11 digits, got nil")
+	}
+}
+
+// RFC 6238 Appendix B
+func TestTOTPRFC6238(t *testing.T) {
+	keys := map[string][]byte{
+		"sha1":   []byte("12345678901234567890"),
+		"sha256": []byte("12345678901234567890123456789012"),
+		"sha512": []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+
+	tests := []struct {
+		time int64
+		algo string
+		want string
+	}{
+		{59, "sha1", "94287082"},
+		{59, "sha256", "46119246"},
+		{59, "sha512", "90693936"},
+		{1111111109, "sha1", "07081804"},
+		{1111111109, "sha256", "68084774"},
+		{1111111109, "sha512", "25091201"},
+		{1111111111, "sha1", "14050471"},
+		{1111111111, "sha256", "67062674"},
+		{1111111111, "sha512", "99943326"},
+		{1234567890, "sha1", "89005924"},
+		{1234567890, "sha256", "91819424"},
+		{1234567890, "sha512", "93441116"},
+		{2000000000, "sha1", "69279037"},
+		{2000000000, "sha256", "90698825"},
+		{2000000000, "sha512", "38618901"},
+		{20000000000, "sha1", "65353130"},
+		{20000000000, "sha256", "77737706"},
+		{20000000000, "sha512", "47863826"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTP(keys[tt.algo], tt.time, 30, 8, tt.algo)
+		if err != nil {
+			t.Fatalf("TOTP(%d, %s) error = %v", tt.time, tt.algo, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTP(%d, %s) = %s, want %s", tt.time, tt.algo, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateHonorsAccountFields(t *testing.T) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	svc := &OTPService{now: func() time.Time { return time.Unix(1111111109, 0) }}
+
+	tests := []struct {
+		name string
+		acc  model.Account
+		want string
+	}{
+		{
+			name: "hotp sha1 counter",
+			acc: model.Account{
+				Secret: enc.EncodeToString([]byte("12345678901234567890")),
+				Type:   model.TypeHOTP, Digits: 6, Algorithm: "sha1", Counter: 9,
+			},
+			want: "520489",
+		},
+		{
+			name: "hotp sha256",
+			acc: model.Account{
+				Secret: enc.EncodeToString([]byte("12345678901234567890123456789012")),
+				Type:   model.TypeHOTP, Digits: 8, Algorithm: "SHA256", Counter: 1111111109 / 30,
+			},
+			want: "68084774",
+		},
+		{
+			name: "totp sha512",
+			acc: model.Account{
+				Secret: enc.EncodeToString([]byte("1234567890123456789012345678901234567890123456789012345678901234")),
+				Type:   model.TypeTOTP, Digits: 8, Algorithm: "sha512", Period: 30,
+			},
+			want: "25091201",
+		},
+		{
+			name: "totp defaults",
+			acc: model.Account{
+				Secret: enc.EncodeToString([]byte("12345678901234567890")),
+				Type:   model.TypeTOTP,
+			},
+			want: "081804",
+		},
+	}
+
+	for _, tt := range tests {
+		res, err := svc.Generate(&tt.acc)
+		if err != nil {
+			t.Fatalf("%s: Generate() error = %v", tt.name, err)
+		}
+		if res.Code != tt.want {
+			t.Errorf("%s: Generate() = %s, want %s", tt.name, res.Code, tt.want)
+		}
+	}
+}
+
+// The vectors come from the ValvePython steam library's guard tests.
+func TestSteam(t *testing.T) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	tests := []struct {
+		time   int64
+		period int64
+		want   string
+	}{
+		{3000030, 30, "YRGQJ"},
+		{3000029, 30, "94R9D"},
+		// A stored period other than 30s must not change the code
+		{3000030, 60, "YRGQJ"},
+	}
+
+	for _, tt := range tests {
+		svc := &OTPService{now: func() time.Time { return time.Unix(tt.time, 0) }}
+		acc := model.Account{
+			Secret: enc.EncodeToString([]byte("superdupersecret")),
+			Type:   model.TypeSteam, Digits: model.SteamDigits, Algorithm: "sha1", Period: tt.period,
+		}
+
+		res, err := svc.Generate(&acc)
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if res.Code != tt.want {
+			t.Errorf("Generate() at %d with period %d = %s, want %s", tt.time, tt.period, res.Code, tt.want)
+		}
+		if res.PeriodEnd-res.PeriodStart != model.SteamPeriod {
+			t.Errorf("period = [%d, %d), want a %ds step", res.PeriodStart, res.PeriodEnd, model.SteamPeriod)
+		}
+	}
+}
+
+// Older vaults and andOTP imports store the type upper-case.
+func TestAdvanceUpperCaseType(t *testing.T) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	acc := model.Account{
+		Secret: enc.EncodeToString([]byte("12345678901234567890")),
+		Type:   "HOTP", Digits: 6, Algorithm: "sha1", Counter: 8,
+	}
+
+	res, err := NewOTPService().Advance(&acc)
+	if err != nil {
+		t.Fatalf("Advance() error = %v", err)
+	}
+	if acc.Counter != 9 || res.Code != "520489" {
+		t.Errorf("Advance() = %s at counter %d, want 520489 at 9", res.Code, acc.Counter)
+	}
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"runtime"
+	"time"
+)
+
+// Calibration bounds. Memory is the main cost since it is what makes
+// Argon2id expensive on GPUs, passes only make up the remaining time.
+const (
+	calibrateMinMemory  = 32 * 1024 // KiB
+	calibrateMaxThreads = 8
+	calibrateMaxTime    = 64
+)
+
+// MeasureFunc times one key derivation with the given parameters.
+type MeasureFunc func(p KDFParams) time.Duration
+
+// MeasureKDF derives a throwaway key and reports how long it took.
+func MeasureKDF(p KDFParams) time.Duration {
+	salt := make([]byte, saltLen)
+	start := time.Now()
+	p.deriveKey([]byte("gauth calibration"), salt)
+	return time.Since(start)
+}
+
+// Calibrate proposes Argon2id parameters whose derivation takes about
+// target on this machine, using up to maxMemory KiB. It uses one thread per
+// CPU, doubles the memory while a single pass stays under half the target,
+// then adds passes to fill the rest. It returns the parameters and their
+// measured duration.
+func Calibrate(target time.Duration, maxMemory uint32, measure MeasureFunc) (KDFParams, time.Duration) {
+	threads := runtime.NumCPU()
+	if threads > calibrateMaxThreads {
+		threads = calibrateMaxThreads
+	}
+	if maxMemory > MaxKDFMemory {
+		maxMemory = MaxKDFMemory
+	}
+	if maxMemory < calibrateMinMemory {
+		maxMemory = calibrateMinMemory
+	}
+
+	p := KDFParams{Time: 1, Memory: calibrateMinMemory, Threads: uint8(threads)}
+	took := measure(p)
+	for took < target/2 && p.Memory*2 <= maxMemory {
+		p.Memory *= 2
+		took = measure(p)
+	}
+
+	if took > 0 && took < target {
+		passes := uint32(target / took)
+		if passes > calibrateMaxTime {
+			passes = calibrateMaxTime
+		}
+		if passes > 1 {
+			p.Time = passes
+			took = measure(p)
+		}
+	}
+	return p, took
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// linearCost pretends every pass over every MiB costs perMiB.
+func linearCost(perMiB time.Duration) MeasureFunc {
+	return func(p KDFParams) time.Duration {
+		return time.Duration(p.Time) * time.Duration(p.Memory/1024) * perMiB
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	tests := []struct {
+		name      string
+		perMiB    time.Duration
+		maxMemory uint32
+		want      KDFParams
+	}{
+		// 1ms per MiB: memory grows to 256 MiB (256ms), then 1 pass fits
+		{"memory bound", time.Millisecond, 1024 * 1024, KDFParams{Time: 1, Memory: 256 * 1024}},
+		// capped at 64 MiB (64ms per pass), passes fill up to 500ms
+		{"memory capped", time.Millisecond, 64 * 1024, KDFParams{Time: 7, Memory: 64 * 1024}},
+		// a slow machine stays at the minimum
+		{"slow machine", 100 * time.Millisecond, 1024 * 1024, KDFParams{Time: 1, Memory: calibrateMinMemory}},
+	}
+
+	for _, tt := range tests {
+		p, took := Calibrate(500*time.Millisecond, tt.maxMemory, linearCost(tt.perMiB))
+		if p.Time != tt.want.Time || p.Memory != tt.want.Memory {
+			t.Errorf("%s: got t=%d m=%d, want t=%d m=%d", tt.name, p.Time, p.Memory, tt.want.Time, tt.want.Memory)
+		}
+		if err := p.Validate(); err != nil {
+			t.Errorf("%s: invalid params: %v", tt.name, err)
+		}
+		if took != linearCost(tt.perMiB)(p) {
+			t.Errorf("%s: reported %v, not the measured duration", tt.name, took)
+		}
+	}
+}
@@ -4,58 +4,150 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
 )
 
+// errDecrypt means authentication failed: the key is wrong or the data was
+// tampered with, which AEADs can't tell apart.
+var errDecrypt = errors.New("decryption failed")
+
 const (
 	saltLen  = 16
 	nonceLen = 12
 	keyLen   = 32
-	timeCost = 3
-	memCost  = 64 * 1024
-	threads  = 4
 )
 
-// encrypt generates a derived key and encrypts data using AES-GCM-256
-func encrypt(data []byte, password string) ([]byte, error) {
-	salt := make([]byte, saltLen)
-	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
-		return nil, err
+// KDF and cipher identifiers recorded in the vault header. Never reuse or
+// renumber them, old vaults depend on them.
+const (
+	KDFArgon2id   uint8 = 1
+	KDFHKDFSHA256 uint8 = 2 // only for high-entropy secrets such as recovery keys
+
+	CipherAES256GCM         uint8 = 1
+	CipherXChaCha20Poly1305 uint8 = 2
+)
+
+// DefaultCipher encrypts new vaults.
+const DefaultCipher = CipherAES256GCM
+
+var cipherNames = map[uint8]string{
+	CipherAES256GCM:         "aes-256-gcm",
+	CipherXChaCha20Poly1305: "xchacha20-poly1305",
+}
+
+// CipherName returns the name of a cipher id.
+func CipherName(id uint8) string {
+	if name, ok := cipherNames[id]; ok {
+		return name
 	}
+	return fmt.Sprintf("unknown cipher %d", id)
+}
 
-	key := argon2.IDKey([]byte(password), salt, timeCost, memCost, threads, keyLen)
+// ParseCipher looks up a cipher by name.
+func ParseCipher(name string) (uint8, error) {
+	for id, n := range cipherNames {
+		if strings.EqualFold(name, n) {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown cipher %q (use aes-256-gcm or xchacha20-poly1305)", name)
+}
 
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
+// KDFParams are the Argon2id costs used to derive the vault key.
+type KDFParams struct {
+	Time    uint32 // number of passes
+	Memory  uint32 // KiB
+	Threads uint8
+}
+
+// DefaultKDFParams are used for new vaults and when upgrading legacy ones.
+var DefaultKDFParams = KDFParams{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// legacyKDFParams are the costs hard-coded before vaults had a header.
+var legacyKDFParams = KDFParams{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// MaxKDFMemory caps the memory, in KiB, a vault header may ask for, so a
+// corrupted or hostile file can't exhaust the machine before the password is
+// checked.
+const MaxKDFMemory = 4 * 1024 * 1024 // 4 GiB
+
+// Validate checks that p is usable and within sane bounds.
+func (p KDFParams) Validate() error {
+	if p.Time < 1 || p.Threads < 1 {
+		return fmt.Errorf("argon2id time and threads must be at least 1")
+	}
+	if p.Memory < 8*uint32(p.Threads) {
+		return fmt.Errorf("argon2id memory must be at least %d KiB for %d threads", 8*uint32(p.Threads), p.Threads)
+	}
+	if p.Memory > MaxKDFMemory {
+		return fmt.Errorf("argon2id memory of %d KiB exceeds the %d KiB limit", p.Memory, MaxKDFMemory)
 	}
+	return nil
+}
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
+func (p KDFParams) String() string {
+	return fmt.Sprintf("argon2id (t=%d, m=%d MiB, p=%d)", p.Time, p.Memory/1024, p.Threads)
+}
+
+func (p KDFParams) deriveKey(secret, salt []byte) []byte {
+	return argon2.IDKey(secret, salt, p.Time, p.Memory, p.Threads, keyLen)
+}
+
+func newAEAD(cipherID uint8, key []byte) (cipher.AEAD, error) {
+	switch cipherID {
+	case CipherAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case CipherXChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
 	}
+	return nil, fmt.Errorf("unsupported cipher id %d", cipherID)
+}
 
-	nonce := make([]byte, nonceLen)
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
 		return nil, err
 	}
+	return b, nil
+}
 
-	// Layout: [salt][nonce][ciphertext]
-	cipherText := gcm.Seal(nil, nonce, data, nil)
+// openDirect opens a version 1 or 2 vault, whose payload is encrypted
+// directly with the key derived from the password.
+func openDirect(h *vaultHeader, data, payload []byte, password string) ([]byte, error) {
+	aead, err := newAEAD(h.cipherID, h.kdf.deriveKey([]byte(password), h.salt))
+	if err != nil {
+		return nil, err
+	}
+	if len(h.nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("corrupted vault header: bad nonce length")
+	}
 
-	out := make([]byte, 0, len(salt)+len(nonce)+len(cipherText))
-	out = append(out, salt...)
-	out = append(out, nonce...)
-	out = append(out, cipherText...)
+	// Version 1 vaults predate authenticating the header
+	var aad []byte
+	if h.version >= 2 {
+		aad = data[:len(data)-len(payload)]
+	}
 
-	return out, nil
+	plain, err := aead.Open(nil, h.nonce, payload, aad)
+	if err != nil {
+		return nil, errDecrypt
+	}
+	return plain, nil
 }
 
-// decrypt derives the key from the password and decrypts the provided data
-func decrypt(data []byte, password string) ([]byte, error) {
+// decryptLegacy opens the [salt][nonce][ciphertext] layout written before
+// vaults had a header.
+func decryptLegacy(data []byte, password string) ([]byte, error) {
 	if len(data) < saltLen+nonceLen {
 		return nil, fmt.Errorf("ciphertext too short")
 	}
@@ -64,21 +156,14 @@ func decrypt(data []byte, password string) ([]byte, error) {
 	nonce := data[saltLen : saltLen+nonceLen]
 	cipherText := data[saltLen+nonceLen:]
 
-	key := argon2.IDKey([]byte(password), salt, timeCost, memCost, threads, keyLen)
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
-
-	gcm, err := cipher.NewGCM(block)
+	aead, err := newAEAD(CipherAES256GCM, legacyKDFParams.deriveKey([]byte(password), salt))
 	if err != nil {
 		return nil, err
 	}
 
-	plainText, err := gcm.Open(nil, nonce, cipherText, nil)
+	plainText, err := aead.Open(nil, nonce, cipherText, nil)
 	if err != nil {
-		return nil, fmt.Errorf("decryption failed: %w", err)
+		return nil, errDecrypt
 	}
 
 	return plainText, nil
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+)
+
+const keyfileLen = 64 // bytes of randomness in generated keyfiles
+
+// credentials are the secrets offered to unlock a vault: a password, a
+// keyfile, or both.
+type credentials struct {
+	password string
+	keyfile  []byte // SHA-256 of the keyfile contents, nil without one
+}
+
+func (c credentials) set() bool {
+	return c.password != "" || c.keyfile != nil
+}
+
+// slotKind is the type of slot that stores these credentials.
+func (c credentials) slotKind() SlotType {
+	switch {
+	case c.password != "" && c.keyfile != nil:
+		return SlotComposite
+	case c.keyfile != nil:
+		return SlotKeyfile
+	}
+	return SlotPassword
+}
+
+// only keeps the parts of c that a slot of the given type uses, so they
+// can be reused to re-wrap it.
+func (c credentials) only(kind SlotType) credentials {
+	switch kind {
+	case SlotKeyfile:
+		return credentials{keyfile: c.keyfile}
+	case SlotComposite:
+		return c
+	}
+	return credentials{password: c.password}
+}
+
+// material returns the secret a slot of the given type derives its key
+// from, and false when c lacks a part of it. Composite keys hash the
+// password and the keyfile hash together, so neither is enough alone.
+func (c credentials) material(kind SlotType) ([]byte, bool) {
+	switch kind {
+	case SlotPassword:
+		return []byte(c.password), c.password != ""
+	case SlotRecovery:
+		key := normalizeRecoveryKey(c.password)
+		return []byte(key), key != ""
+	case SlotKeyfile:
+		return c.keyfile, c.keyfile != nil
+	case SlotComposite:
+		if c.password == "" || c.keyfile == nil {
+			return nil, false
+		}
+		pw := sha256.Sum256([]byte(c.password))
+		sum := sha256.Sum256(append(pw[:], c.keyfile...))
+		return sum[:], true
+	}
+	return nil, false
+}
+
+// hashKeyfile reads a keyfile. Any non-empty file works; only its SHA-256
+// is used.
+func hashKeyfile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyfile: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("keyfile %s is empty", path)
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+// GenerateKeyfile writes a new random keyfile to path. An existing file is
+// only replaced when overwrite is set.
+func GenerateKeyfile(path string, overwrite bool) error {
+	data, err := randomBytes(keyfileLen)
+	if err != nil {
+		return err
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// UseKeyfile adds the keyfile at path to the credentials used to read and
+// write the vault. With a keyfile, an empty password unlocks keyfile-only
+// slots instead of meaning "unencrypted".
+func (s *Storage) UseKeyfile(path string) error {
+	hash, err := hashKeyfile(path)
+	if err != nil {
+		return err
+	}
+	s.keyfile = hash
+	return nil
+}
+
+// NeedsPassword reports whether unlocking takes a password on top of the
+// keyfile set with UseKeyfile, that is whether the vault lacks a
+// keyfile-only slot.
+func (s *Storage) NeedsPassword() (bool, error) {
+	if s.keyfile == nil {
+		return true, nil
+	}
+	data, err := os.ReadFile(s.dbFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !isVault(data) {
+		return true, nil
+	}
+
+	// A corrupted header is reported by ReadAccounts
+	h, _, err := parseHeader(data)
+	if err != nil {
+		return true, nil
+	}
+	for _, slot := range h.slots {
+		if slot.kind == SlotKeyfile {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// AddKeyfileSlot lets the keyfile at path unlock the vault, together with
+// password or, when it is empty, alone. Call WriteAccounts to save the
+// change.
+func (s *Storage) AddKeyfileSlot(path, password string) error {
+	hash, err := hashKeyfile(path)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureSlots(); err != nil {
+		return err
+	}
+
+	c := credentials{password: password, keyfile: hash}
+	slot, err := newSlot(c.slotKind(), c, s.dataKey, s.KDFParams(), s.Cipher())
+	if err != nil {
+		return err
+	}
+	s.slots = append(s.slots, slot)
+	return nil
+}
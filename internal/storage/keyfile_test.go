@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newKeyfile generates a keyfile in a temporary directory.
+func newKeyfile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "gauth.key")
+	if err := GenerateKeyfile(path, false); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// reopenWith is reopen with a keyfile, or none when keyfile is empty.
+func reopenWith(t *testing.T, s *Storage, password, keyfile string) (*Storage, error) {
+	t.Helper()
+	fresh := &Storage{baseDir: s.baseDir, dbFile: s.dbFile, unlocked: -1}
+	if keyfile != "" {
+		if err := fresh.UseKeyfile(keyfile); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := fresh.ReadAccounts(password)
+	return fresh, err
+}
+
+func TestCompositeKey(t *testing.T) {
+	keyfile, otherKeyfile := newKeyfile(t), newKeyfile(t)
+	s := testStorage(t)
+	if err := s.UseKeyfile(keyfile); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteAccounts(vaultAccounts, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if infos := s.Slots(); len(infos) != 1 || infos[0].Type != SlotComposite {
+		t.Fatalf("unexpected slots %+v", infos)
+	}
+	if need, _ := s.NeedsPassword(); !need {
+		t.Error("composite vault should need a password")
+	}
+
+	for _, password := range []string{"pw", "wrong", ""} {
+		for _, kf := range []string{keyfile, otherKeyfile, ""} {
+			if password == "" && kf == "" {
+				continue
+			}
+			_, err := reopenWith(t, s, password, kf)
+			if password == "pw" && kf == keyfile {
+				if err != nil {
+					t.Errorf("right password and keyfile: %v", err)
+				}
+				continue
+			}
+			if err == nil {
+				t.Errorf("password %q with keyfile %q unlocked the vault", password, kf)
+			} else if kf != "" && !strings.Contains(err.Error(), "wrong password or keyfile") {
+				t.Errorf("password %q with keyfile %q: got %v", password, kf, err)
+			}
+		}
+	}
+}
+
+func TestKeyfileOnlyVault(t *testing.T) {
+	keyfile, otherKeyfile := newKeyfile(t), newKeyfile(t)
+	s := testStorage(t)
+	if err := s.UseKeyfile(keyfile); err != nil {
+		t.Fatal(err)
+	}
+
+	// An empty password with a keyfile still encrypts
+	if err := s.WriteAccounts(vaultAccounts, ""); err != nil {
+		t.Fatal(err)
+	}
+	if enc, _ := s.IsEncrypted(); !enc {
+		t.Fatal("keyfile-only vault is not encrypted")
+	}
+	if infos := s.Slots(); len(infos) != 1 || infos[0].Type != SlotKeyfile {
+		t.Fatalf("unexpected slots %+v", infos)
+	}
+	if need, _ := s.NeedsPassword(); need {
+		t.Error("keyfile-only vault should not need a password")
+	}
+
+	// The keyfile alone is enough, whatever password comes with it
+	for _, password := range []string{"", "anything"} {
+		if _, err := reopenWith(t, s, password, keyfile); err != nil {
+			t.Errorf("keyfile with password %q: %v", password, err)
+		}
+	}
+	for _, password := range []string{"", "anything"} {
+		if _, err := reopenWith(t, s, password, otherKeyfile); err == nil {
+			t.Errorf("wrong keyfile with password %q unlocked the vault", password)
+		}
+	}
+	if _, err := reopenWith(t, s, "anything", ""); err == nil {
+		t.Error("password without keyfile unlocked the vault")
+	}
+	if _, err := reopenWith(t, s, "", ""); err == nil || !strings.Contains(err.Error(), "provide a password") {
+		t.Errorf("no credentials: got %v", err)
+	}
+
+	// Writing back keeps the keyfile slot
+	s, err := reopenWith(t, s, "", keyfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteAccounts(vaultAccounts, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopenWith(t, s, "", keyfile); err != nil {
+		t.Errorf("keyfile no longer unlocks after a write: %v", err)
+	}
+}
+
+func TestKeyfileSlots(t *testing.T) {
+	keyfile, compositeKeyfile := newKeyfile(t), newKeyfile(t)
+	s := testStorage(t)
+	if err := s.WriteAccounts(vaultAccounts, "pw"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := reopen(t, s, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddKeyfileSlot(keyfile, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddKeyfileSlot(compositeKeyfile, "second"); err != nil {
+		t.Fatal(err)
+	}
+	recovery, err := s.AddRecoverySlot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteAccounts(vaultAccounts, "pw"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		password, keyfile string
+		ok                bool
+	}{
+		{"pw", "", true},
+		{"pw", keyfile, true},
+		{"", keyfile, true},
+		{"second", compositeKeyfile, true},
+		{recovery, "", true},
+		{"second", "", false},
+		{"", compositeKeyfile, false},
+		{"second", keyfile, true}, // the keyfile-only slot ignores the password
+		{"wrong", compositeKeyfile, false},
+	}
+	for _, tt := range tests {
+		_, err := reopenWith(t, s, tt.password, tt.keyfile)
+		if (err == nil) != tt.ok {
+			t.Errorf("password %q, keyfile %q: got %v, want ok=%v", tt.password, tt.keyfile, err, tt.ok)
+		}
+	}
+
+	// New credentials replace the password and keyfile slots, not recovery
+	s, err = reopenWith(t, s, "second", compositeKeyfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteAccounts(vaultAccounts, "third"); err != nil {
+		t.Fatal(err)
+	}
+	infos := s.Slots()
+	if len(infos) != 2 || infos[0].Type != SlotRecovery || infos[1].Type != SlotComposite || !infos[1].Unlocked {
+		t.Errorf("unexpected slots %+v", infos)
+	}
+	for _, secret := range [][2]string{{"pw", ""}, {"", keyfile}, {"second", compositeKeyfile}, {"third", ""}} {
+		if _, err := reopenWith(t, s, secret[0], secret[1]); err == nil {
+			t.Errorf("password %q, keyfile %q still unlocks", secret[0], secret[1])
+		}
+	}
+	if _, err := reopenWith(t, s, "third", compositeKeyfile); err != nil {
+		t.Errorf("new composite key does not unlock: %v", err)
+	}
+	if _, err := reopen(t, s, recovery); err != nil {
+		t.Errorf("recovery key does not unlock: %v", err)
+	}
+}
+
+func TestGenerateKeyfile(t *testing.T) {
+	path := newKeyfile(t)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != keyfileLen || info.Mode().Perm() != 0600 {
+		t.Errorf("keyfile has size %d and mode %v", info.Size(), info.Mode().Perm())
+	}
+
+	before, _ := hashKeyfile(path)
+	if err := GenerateKeyfile(path, false); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("overwrote an existing keyfile: %v", err)
+	}
+	if err := GenerateKeyfile(path, true); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := hashKeyfile(path); string(after) == string(before) {
+		t.Error("overwrite kept the old keyfile")
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := testStorage(t).UseKeyfile(empty); err == nil {
+		t.Error("empty keyfile accepted")
+	}
+}
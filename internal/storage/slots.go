@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SlotType says what kind of secret a key slot is unlocked with.
+type SlotType uint8
+
+const (
+	SlotPassword  SlotType = 1
+	SlotRecovery  SlotType = 2
+	SlotKeyfile   SlotType = 3
+	SlotComposite SlotType = 4 // password and keyfile together
+)
+
+func (t SlotType) String() string {
+	switch t {
+	case SlotPassword:
+		return "password"
+	case SlotRecovery:
+		return "recovery key"
+	case SlotKeyfile:
+		return "keyfile"
+	case SlotComposite:
+		return "password + keyfile"
+	}
+	return fmt.Sprintf("unknown (%d)", uint8(t))
+}
+
+// keySlot wraps the vault data key with a key derived from one secret.
+type keySlot struct {
+	kind     SlotType
+	kdfID    uint8
+	kdf      KDFParams
+	salt     []byte
+	cipherID uint8
+	nonce    []byte
+	wrapped  []byte
+}
+
+// SlotInfo describes a key slot without exposing any key material.
+type SlotInfo struct {
+	Type SlotType
+	KDF  string
+	// Argon2id holds the slot's costs, zero for slots that don't use it
+	Argon2id KDFParams
+	// Unlocked is set on the slot the vault was opened with
+	Unlocked bool
+}
+
+const recoveryKeyLen = 20 // bytes, 32 Base32 characters
+
+// newSlot wraps dataKey for the part of c that kind uses. Recovery keys are
+// random enough for HKDF; everything else is stretched with params, since
+// any file can serve as a keyfile.
+func newSlot(kind SlotType, c credentials, dataKey []byte, params KDFParams, cipherID uint8) (keySlot, error) {
+	s := keySlot{kind: kind, cipherID: cipherID}
+	switch kind {
+	case SlotPassword, SlotKeyfile, SlotComposite:
+		if err := params.Validate(); err != nil {
+			return s, err
+		}
+		s.kdfID, s.kdf = KDFArgon2id, params
+	case SlotRecovery:
+		s.kdfID = KDFHKDFSHA256
+	default:
+		return s, fmt.Errorf("unsupported slot type %d", kind)
+	}
+
+	secret, ok := c.material(kind)
+	if !ok {
+		return s, fmt.Errorf("missing the secret for a %s slot", kind)
+	}
+
+	salt, err := randomBytes(saltLen)
+	if err != nil {
+		return s, err
+	}
+	s.salt = salt
+
+	key, err := s.deriveKey(secret)
+	if err != nil {
+		return s, err
+	}
+	aead, err := newAEAD(cipherID, key)
+	if err != nil {
+		return s, err
+	}
+	if s.nonce, err = randomBytes(aead.NonceSize()); err != nil {
+		return s, err
+	}
+	s.wrapped = aead.Seal(nil, s.nonce, dataKey, []byte{byte(kind)})
+	return s, nil
+}
+
+func (s *keySlot) deriveKey(secret []byte) ([]byte, error) {
+	switch s.kdfID {
+	case KDFArgon2id:
+		if err := s.kdf.Validate(); err != nil {
+			return nil, fmt.Errorf("corrupted key slot: %w", err)
+		}
+		return s.kdf.deriveKey(secret, s.salt), nil
+	case KDFHKDFSHA256:
+		return hkdf.Key(sha256.New, secret, s.salt, "gauth key slot", keyLen)
+	}
+	return nil, fmt.Errorf("unsupported key derivation id %d", s.kdfID)
+}
+
+// unwrap returns the data key if c opens this slot.
+func (s *keySlot) unwrap(c credentials) ([]byte, error) {
+	secret, ok := c.material(s.kind)
+	if !ok {
+		return nil, errDecrypt
+	}
+	key, err := s.deriveKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(s.cipherID, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("corrupted key slot: bad nonce length")
+	}
+	dataKey, err := aead.Open(nil, s.nonce, s.wrapped, []byte{byte(s.kind)})
+	if err != nil {
+		return nil, errDecrypt
+	}
+	return dataKey, nil
+}
+
+// unwrapAny tries c on every slot it has the secrets for, the cheap
+// recovery slots first, and returns the data key and the index of the slot
+// that opened. A wrong secret wins over other errors, since it is the
+// likely cause.
+func unwrapAny(slots []keySlot, c credentials) ([]byte, int, error) {
+	var lastErr error
+	wrongKey, tried := false, false
+	for _, cheap := range []bool{true, false} {
+		for i := range slots {
+			if _, ok := c.material(slots[i].kind); !ok || (slots[i].kdfID == KDFHKDFSHA256) != cheap {
+				continue
+			}
+			tried = true
+			key, err := slots[i].unwrap(c)
+			if err == nil {
+				return key, i, nil
+			}
+			wrongKey = wrongKey || errors.Is(err, errDecrypt)
+			lastErr = err
+		}
+	}
+	if wrongKey || !tried {
+		return nil, -1, errDecrypt
+	}
+	return nil, -1, lastErr
+}
+
+// newRecoveryKey returns a random key formatted for writing down, such as
+// "ABCD-EFGH-...".
+func newRecoveryKey() (string, error) {
+	raw, err := randomBytes(recoveryKeyLen)
+	if err != nil {
+		return "", err
+	}
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+
+	var groups []string
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// normalizeRecoveryKey lets recovery keys be typed in any case, with or
+// without separators.
+func normalizeRecoveryKey(key string) string {
+	key = strings.ToUpper(key)
+	return strings.NewReplacer("-", "", " ", "").Replace(key)
+}
+
+// Slots describes the key slots of the vault last read or written.
+func (s *Storage) Slots() []SlotInfo {
+	infos := make([]SlotInfo, len(s.slots))
+	for i, slot := range s.slots {
+		infos[i] = SlotInfo{Type: slot.kind, Unlocked: i == s.unlocked}
+		switch slot.kdfID {
+		case KDFArgon2id:
+			infos[i].KDF = slot.kdf.String()
+			infos[i].Argon2id = slot.kdf
+		case KDFHKDFSHA256:
+			infos[i].KDF = "hkdf-sha256"
+		}
+		infos[i].KDF += ", " + CipherName(slot.cipherID)
+	}
+	return infos
+}
+
+// ensureSlots turns a vault that predates key slots into one with a data
+// key and a password slot for the secret it was opened with.
+func (s *Storage) ensureSlots() error {
+	if s.dataKey != nil {
+		return nil
+	}
+	if !s.creds.set() {
+		return fmt.Errorf("database is not encrypted, set a master password first")
+	}
+	return s.prepareKeys(s.password)
+}
+
+// AddPasswordSlot lets password unlock the vault too. Call WriteAccounts to
+// save the change.
+func (s *Storage) AddPasswordSlot(password string) error {
+	if password == "" {
+		return fmt.Errorf("password must not be empty")
+	}
+	if err := s.ensureSlots(); err != nil {
+		return err
+	}
+	slot, err := newSlot(SlotPassword, credentials{password: password}, s.dataKey, s.KDFParams(), s.Cipher())
+	if err != nil {
+		return err
+	}
+	s.slots = append(s.slots, slot)
+	return nil
+}
+
+// AddRecoverySlot generates a recovery key that unlocks the vault and
+// returns it. It is not stored anywhere else. Call WriteAccounts to save
+// the change.
+func (s *Storage) AddRecoverySlot() (string, error) {
+	if err := s.ensureSlots(); err != nil {
+		return "", err
+	}
+	key, err := newRecoveryKey()
+	if err != nil {
+		return "", err
+	}
+	slot, err := newSlot(SlotRecovery, credentials{password: key}, s.dataKey, KDFParams{}, s.Cipher())
+	if err != nil {
+		return "", err
+	}
+	s.slots = append(s.slots, slot)
+	return key, nil
+}
+
+// RemoveSlot deletes the slot at index i. The last slot can't be removed.
+// Call WriteAccounts to save the change.
+func (s *Storage) RemoveSlot(i int) error {
+	if i < 0 || i >= len(s.slots) {
+		return fmt.Errorf("no key slot %d", i+1)
+	}
+	if len(s.slots) == 1 {
+		return fmt.Errorf("cannot remove the last key slot, use 'gauth passwd' to remove encryption")
+	}
+
+	s.slots = append(s.slots[:i:i], s.slots[i+1:]...)
+	switch {
+	case i == s.unlocked:
+		s.unlocked = -1
+	case i < s.unlocked:
+		s.unlocked--
+	}
+	return nil
+}
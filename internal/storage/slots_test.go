@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+// reopen reads the vault from disk with a fresh Storage, like a new run.
+func reopen(t *testing.T, s *Storage, secret string) (*Storage, error) {
+	t.Helper()
+	fresh := &Storage{baseDir: s.baseDir, dbFile: s.dbFile, unlocked: -1}
+	_, err := fresh.ReadAccounts(secret)
+	return fresh, err
+}
+
+func TestKeySlots(t *testing.T) {
+	s := testStorage(t)
+	if err := s.WriteAccounts(vaultAccounts, "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second password and a recovery key, added without re-entering the first
+	s, err := reopen(t, s, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddPasswordSlot("second"); err != nil {
+		t.Fatal(err)
+	}
+	recovery, err := s.AddRecoverySlot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteAccounts(vaultAccounts, "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"first", "second", recovery, strings.ToLower(strings.ReplaceAll(recovery, "-", " "))} {
+		if _, err := reopen(t, s, secret); err != nil {
+			t.Errorf("%q does not unlock: %v", secret, err)
+		}
+	}
+	if _, err := reopen(t, s, "third"); err == nil || !strings.Contains(err.Error(), "wrong password") {
+		t.Errorf("wrong password: got %v", err)
+	}
+
+	infos := s.Slots()
+	if len(infos) != 3 || infos[0].Type != SlotPassword || infos[2].Type != SlotRecovery || !infos[0].Unlocked || infos[1].Unlocked {
+		t.Errorf("unexpected slots %+v", infos)
+	}
+
+	// Removing the second password only affects that slot
+	if err := s.RemoveSlot(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteAccounts(vaultAccounts, "first"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopen(t, s, "second"); err == nil {
+		t.Error("removed password still unlocks")
+	}
+	if _, err := reopen(t, s, recovery); err != nil {
+		t.Errorf("recovery key lost: %v", err)
+	}
+}
+
+func TestRecoveryKeyResetsPassword(t *testing.T) {
+	s := testStorage(t)
+	if err := s.WriteAccounts(vaultAccounts, "forgotten"); err != nil {
+		t.Fatal(err)
+	}
+	recovery, err := s.AddRecoverySlot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteAccounts(vaultAccounts, "forgotten"); err != nil {
+		t.Fatal(err)
+	}
+
+	// What 'gauth passwd' does after unlocking with the recovery key
+	s, err = reopen(t, s, recovery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteAccounts(vaultAccounts, "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := reopen(t, s, "forgotten"); err == nil {
+		t.Error("old password still unlocks after the reset")
+	}
+	for _, secret := range []string{"new", recovery} {
+		if _, err := reopen(t, s, secret); err != nil {
+			t.Errorf("%q does not unlock: %v", secret, err)
+		}
+	}
+}
+
+func TestSlotChangesKeepTheDataKey(t *testing.T) {
+	s := testStorage(t)
+	if err := s.WriteAccounts(vaultAccounts, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	key := s.dataKey
+
+	if err := s.SetKDFParams(KDFParams{Time: 2, Memory: 64, Threads: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteAccounts(vaultAccounts, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteAccounts(vaultAccounts, "changed"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := reopen(t, s, "changed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(s.dataKey) != string(key) {
+		t.Error("changing the password or costs replaced the data key")
+	}
+	if s.slots[0].kdf.Time != 2 {
+		t.Errorf("slot not re-wrapped with the new costs: %+v", s.slots[0].kdf)
+	}
+}
+
+func TestNewCostsRewrapSlotsTheSecretOpens(t *testing.T) {
+	s := testStorage(t)
+	if err := s.WriteAccounts(vaultAccounts, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	for _, password := range []string{"other", "pw"} {
+		if err := s.AddPasswordSlot(password); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.WriteAccounts(vaultAccounts, "pw"); err != nil {
+		t.Fatal(err)
+	}
+
+	// What 'gauth kdf calibrate' does
+	s, err := reopen(t, s, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stronger := KDFParams{Time: 2, Memory: 64, Threads: 1}
+	if err := s.SetKDFParams(stronger); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteAccounts(vaultAccounts, "pw"); err != nil {
+		t.Fatal(err)
+	}
+
+	infos := s.Slots()
+	if len(infos) != 3 || infos[0].Argon2id != stronger || infos[1].Argon2id != fastKDF || infos[2].Argon2id != stronger {
+		t.Errorf("unexpected slots %+v", infos)
+	}
+	for _, password := range []string{"pw", "other"} {
+		if _, err := reopen(t, s, password); err != nil {
+			t.Errorf("%q does not unlock: %v", password, err)
+		}
+	}
+}
+
+func TestRemoveLastSlot(t *testing.T) {
+	s := testStorage(t)
+	if err := s.WriteAccounts(vaultAccounts, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveSlot(0); err == nil {
+		t.Error("removed the last key slot")
+	}
+	if err := s.RemoveSlot(5); err == nil {
+		t.Error("removed a slot that doesn't exist")
+	}
+}
+
+func TestSlotsNeedEncryption(t *testing.T) {
+	s := testStorage(t)
+	if err := s.WriteAccounts(vaultAccounts, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddRecoverySlot(); err == nil {
+		t.Error("added a recovery key to a plain database")
+	}
+}
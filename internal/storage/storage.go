@@ -2,6 +2,7 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,6 +13,24 @@ import (
 type Storage struct {
 	baseDir string
 	dbFile  string
+
+	// kdf and cipher hold the parameters of the vault last read, so
+	// writing it back keeps them. Zero means the defaults.
+	kdf    KDFParams
+	cipher uint8
+
+	// keyfile is the hash of the keyfile set with UseKeyfile
+	keyfile []byte
+
+	// Envelope state of the vault last read: the data key, its key slots,
+	// the password it was read with, the credentials of the slot that
+	// unlocked it and that slot's index (-1 when none did, e.g. for vaults
+	// that predate slots).
+	dataKey  []byte
+	slots    []keySlot
+	password string
+	creds    credentials
+	unlocked int
 }
 
 func NewStorage() (*Storage, error) {
@@ -24,8 +43,9 @@ func NewStorage() (*Storage, error) {
 	dbFile := filepath.Join(baseDir, "gauth.json")
 
 	return &Storage{
-		baseDir: baseDir,
-		dbFile:  dbFile,
+		baseDir:  baseDir,
+		dbFile:   dbFile,
+		unlocked: -1,
 	}, nil
 }
 
@@ -42,9 +62,50 @@ func (s *Storage) IsEncrypted() (bool, error) {
 		return false, err
 	}
 
+	if isVault(data) {
+		return true, nil
+	}
+
+	// Legacy vaults have no header, anything that isn't JSON is one
 	var accounts []model.Account
 	err = json.Unmarshal(data, &accounts)
-	return err != nil, nil // If error, it's likely encrypted
+	return err != nil, nil
+}
+
+// KDFParams returns the key derivation parameters the next encrypted write
+// will use.
+func (s *Storage) KDFParams() KDFParams {
+	if s.kdf == (KDFParams{}) {
+		return DefaultKDFParams
+	}
+	return s.kdf
+}
+
+// Cipher returns the cipher id the next encrypted write will use.
+func (s *Storage) Cipher() uint8 {
+	if s.cipher == 0 {
+		return DefaultCipher
+	}
+	return s.cipher
+}
+
+// SetCipher changes the cipher for the next encrypted write.
+func (s *Storage) SetCipher(id uint8) error {
+	if _, ok := cipherNames[id]; !ok {
+		return fmt.Errorf("unsupported cipher id %d", id)
+	}
+	s.cipher = id
+	return nil
+}
+
+// SetKDFParams changes the key derivation parameters for the next encrypted
+// write.
+func (s *Storage) SetKDFParams(p KDFParams) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	s.kdf = p
+	return nil
 }
 
 func (s *Storage) ReadAccounts(password string) ([]model.Account, error) {
@@ -61,16 +122,38 @@ func (s *Storage) ReadAccounts(password string) ([]model.Account, error) {
 		return accounts, nil
 	}
 
-	if password == "" {
+	if password == "" && s.keyfile == nil {
 		return nil, fmt.Errorf("database is encrypted, please provide a password")
 	}
 
-	decrypted, err := decrypt(data, password)
-	if err != nil {
+	c := credentials{password: password, keyfile: s.keyfile}
+	vault, err := openVault(data, c)
+	if errors.Is(err, errDecrypt) {
+		if s.keyfile != nil {
+			return nil, fmt.Errorf("failed to decrypt database (wrong password or keyfile?): %w", err)
+		}
 		return nil, fmt.Errorf("failed to decrypt database (wrong password?): %w", err)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database: %w", err)
+	}
+
+	s.dataKey, s.slots, s.password, s.unlocked = vault.dataKey, nil, password, vault.slot
+	s.creds = c.only(SlotPassword)
+	if h := vault.header; h != nil {
+		s.cipher, s.slots = h.cipherID, h.slots
+		if h.version < 3 {
+			s.kdf = h.kdf
+		} else if vault.slot >= 0 {
+			slot := h.slots[vault.slot]
+			s.creds = c.only(slot.kind)
+			if slot.kdfID == KDFArgon2id {
+				s.kdf = slot.kdf
+			}
+		}
+	}
 
-	if err := json.Unmarshal(decrypted, &accounts); err != nil {
+	if err := json.Unmarshal(vault.plain, &accounts); err != nil {
 		return nil, fmt.Errorf("failed to parse decrypted database: %w", err)
 	}
 
@@ -88,8 +171,13 @@ func (s *Storage) WriteAccounts(accounts []model.Account, password string) error
 	}
 
 	finalData := data
-	if password != "" {
-		encrypted, err := encrypt(data, password)
+	if password == "" && s.keyfile == nil {
+		s.dataKey, s.slots, s.password, s.creds, s.unlocked = nil, nil, "", credentials{}, -1
+	} else {
+		if err := s.prepareKeys(password); err != nil {
+			return fmt.Errorf("failed to encrypt accounts: %w", err)
+		}
+		encrypted, err := sealVault(data, s.dataKey, s.Cipher(), s.slots)
 		if err != nil {
 			return fmt.Errorf("failed to encrypt accounts: %w", err)
 		}
@@ -109,6 +197,68 @@ func (s *Storage) WriteAccounts(accounts []model.Account, password string) error
 	return nil
 }
 
+// prepareKeys makes sure password, with the keyfile if one is set, unlocks
+// the vault before it is written. Vaults without a data key get a new one
+// with a single slot. A password other than the one the vault was read with
+// replaces every password and keyfile slot, which is how credentials are
+// changed; recovery slots keep working. When the KDF costs have changed,
+// the same password re-wraps the slot it unlocked and every other Argon2id
+// slot it opens; slots with other secrets keep their old costs.
+func (s *Storage) prepareKeys(password string) error {
+	c := credentials{password: password, keyfile: s.keyfile}
+	unchanged := s.creds.set() && password == s.password
+	if unchanged {
+		c = s.creds
+	}
+
+	if s.dataKey == nil || !unchanged {
+		key := s.dataKey
+		if key == nil {
+			var err error
+			if key, err = randomBytes(keyLen); err != nil {
+				return err
+			}
+		}
+		slot, err := newSlot(c.slotKind(), c, key, s.KDFParams(), s.Cipher())
+		if err != nil {
+			return err
+		}
+		var kept []keySlot
+		for _, existing := range s.slots {
+			if existing.kind == SlotRecovery {
+				kept = append(kept, existing)
+			}
+		}
+		s.dataKey, s.slots = key, append(kept, slot)
+		s.password, s.creds, s.unlocked = password, c, len(s.slots)-1
+		return nil
+	}
+
+	if s.unlocked < 0 {
+		return nil
+	}
+	current := s.slots[s.unlocked]
+	if current.kdfID != KDFArgon2id || current.kdf == s.KDFParams() {
+		return nil
+	}
+	for i, existing := range s.slots {
+		if existing.kdfID != KDFArgon2id || existing.kdf == s.KDFParams() {
+			continue
+		}
+		if i != s.unlocked {
+			if _, err := existing.unwrap(s.creds); err != nil {
+				continue
+			}
+		}
+		slot, err := newSlot(existing.kind, s.creds, s.dataKey, s.KDFParams(), s.Cipher())
+		if err != nil {
+			return err
+		}
+		s.slots[i] = slot
+	}
+	return nil
+}
+
 func (s *Storage) GetFileLocation() string {
 	return s.dbFile
 }
@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// An encrypted vault starts with a self-describing header and is followed by
+// the ciphertext. Integers are big-endian, byte strings are prefixed with a
+// u8 length, and every version below is still read.
+//
+// Versions 1 and 2 encrypt the payload directly with the password key:
+//
+//	magic    "GAUTH"
+//	version  u8
+//	kdf      u8 id, then for argon2id: u32 time, u32 memory (KiB), u8 threads
+//	salt     bytes
+//	cipher   u8 id
+//	nonce    bytes
+//
+// Version 3 encrypts it with a random data key, wrapped by one or more key
+// slots that can each unlock the vault:
+//
+//	magic    "GAUTH"
+//	version  u8
+//	cipher   u8 id
+//	slots    u8 count, then per slot:
+//	           u8 type, kdf (as above, hkdf-sha256 has no parameters),
+//	           salt bytes, u8 cipher id, nonce bytes, wrapped key bytes
+//	nonce    bytes
+//
+// Version 1 doesn't authenticate its header; later versions pass all of it
+// as additional data, so it can't be edited or downgraded unnoticed. Files
+// without the magic are either plain JSON or the legacy
+// [salt][nonce][ciphertext] blob.
+var vaultMagic = []byte("GAUTH")
+
+const vaultVersion = 3
+
+type vaultHeader struct {
+	version  uint8
+	cipherID uint8
+	nonce    []byte
+
+	// Versions 1 and 2 derive the payload key from the password directly
+	kdfID uint8
+	kdf   KDFParams
+	salt  []byte
+
+	// Version 3 wraps a data key in key slots
+	slots []keySlot
+}
+
+func isVault(data []byte) bool {
+	return bytes.HasPrefix(data, vaultMagic)
+}
+
+func (h *vaultHeader) marshal() []byte {
+	b := append([]byte{}, vaultMagic...)
+	b = append(b, h.version)
+	if h.version < 3 {
+		b = appendKDF(b, h.kdfID, h.kdf)
+		b = appendBytes(b, h.salt)
+		b = append(b, h.cipherID)
+		return appendBytes(b, h.nonce)
+	}
+
+	b = append(b, h.cipherID, byte(len(h.slots)))
+	for _, s := range h.slots {
+		b = append(b, byte(s.kind))
+		b = appendKDF(b, s.kdfID, s.kdf)
+		b = appendBytes(b, s.salt)
+		b = append(b, s.cipherID)
+		b = appendBytes(b, s.nonce)
+		b = appendBytes(b, s.wrapped)
+	}
+	return appendBytes(b, h.nonce)
+}
+
+func appendKDF(b []byte, id uint8, p KDFParams) []byte {
+	b = append(b, id)
+	if id == KDFArgon2id {
+		b = binary.BigEndian.AppendUint32(b, p.Time)
+		b = binary.BigEndian.AppendUint32(b, p.Memory)
+		b = append(b, p.Threads)
+	}
+	return b
+}
+
+func appendBytes(b, field []byte) []byte {
+	return append(append(b, byte(len(field))), field...)
+}
+
+// parseHeader decodes the vault header and returns it with the ciphertext
+// that follows.
+func parseHeader(data []byte) (*vaultHeader, []byte, error) {
+	r := &byteReader{data: data[len(vaultMagic):]}
+	h := &vaultHeader{}
+
+	h.version = r.u8()
+	if r.err == nil && (h.version < 1 || h.version > vaultVersion) {
+		return nil, nil, fmt.Errorf("unsupported vault version %d, please upgrade gauth", h.version)
+	}
+
+	if h.version < 3 {
+		h.kdfID, h.kdf = r.kdf()
+		if r.err == nil && h.kdfID != KDFArgon2id {
+			return nil, nil, fmt.Errorf("unsupported key derivation id %d", h.kdfID)
+		}
+		h.salt = r.bytes()
+		h.cipherID = r.u8()
+		h.nonce = r.bytes()
+	} else {
+		h.cipherID = r.u8()
+		count := int(r.u8())
+		for i := 0; i < count && r.err == nil; i++ {
+			var s keySlot
+			s.kind = SlotType(r.u8())
+			s.kdfID, s.kdf = r.kdf()
+			s.salt = r.bytes()
+			s.cipherID = r.u8()
+			s.nonce = r.bytes()
+			s.wrapped = r.bytes()
+			h.slots = append(h.slots, s)
+		}
+		h.nonce = r.bytes()
+	}
+
+	if r.err != nil {
+		return nil, nil, fmt.Errorf("corrupted vault header: %w", r.err)
+	}
+	if h.version < 3 {
+		if err := h.kdf.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("corrupted vault header: %w", err)
+		}
+	}
+	return h, r.data, nil
+}
+
+// byteReader reads header fields, remembering the first error so callers
+// only check once.
+type byteReader struct {
+	data []byte
+	err  error
+}
+
+func (r *byteReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.err = fmt.Errorf("unexpected end of data")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *byteReader) u8() uint8 {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *byteReader) u32() uint32 {
+	if b := r.take(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// bytes reads a u8 length-prefixed field.
+func (r *byteReader) bytes() []byte {
+	return r.take(int(r.u8()))
+}
+
+// kdf reads a KDF id and its parameters.
+func (r *byteReader) kdf() (uint8, KDFParams) {
+	var p KDFParams
+	id := r.u8()
+	if id == KDFArgon2id {
+		p.Time = r.u32()
+		p.Memory = r.u32()
+		p.Threads = r.u8()
+	}
+	return id, p
+}
+
+// sealVault encrypts data with the data key into a version 3 vault.
+func sealVault(data, dataKey []byte, cipherID uint8, slots []keySlot) ([]byte, error) {
+	aead, err := newAEAD(cipherID, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomBytes(aead.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+
+	h := &vaultHeader{version: vaultVersion, cipherID: cipherID, nonce: nonce, slots: slots}
+	header := h.marshal()
+	return aead.Seal(header, nonce, data, header), nil
+}
+
+// unlocked is an opened vault. dataKey is nil for vaults that predate key
+// slots, slot is the index of the slot that opened it.
+type unlocked struct {
+	plain   []byte
+	header  *vaultHeader
+	dataKey []byte
+	slot    int
+}
+
+// openVault decrypts any vault version, or a legacy headerless blob, with
+// c. Only version 3 vaults can use a keyfile. The header is nil for legacy
+// blobs.
+func openVault(data []byte, c credentials) (*unlocked, error) {
+	if !isVault(data) {
+		plain, err := decryptLegacy(data, c.password)
+		if err != nil {
+			return nil, err
+		}
+		return &unlocked{plain: plain, slot: -1}, nil
+	}
+
+	h, payload, err := parseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.version < 3 {
+		plain, err := openDirect(h, data, payload, c.password)
+		if err != nil {
+			return nil, err
+		}
+		return &unlocked{plain: plain, header: h, slot: -1}, nil
+	}
+
+	dataKey, slot, err := unwrapAny(h.slots, c)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(h.cipherID, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(h.nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("corrupted vault header: bad nonce length")
+	}
+
+	plain, err := aead.Open(nil, h.nonce, payload, data[:len(data)-len(payload)])
+	if err != nil {
+		return nil, errDecrypt
+	}
+	return &unlocked{plain: plain, header: h, dataKey: dataKey, slot: slot}, nil
+}
@@ -0,0 +1,289 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/leeineian/gauth/internal/model"
+)
+
+// fastKDF keeps the tests quick; the format doesn't care about the costs.
+var fastKDF = KDFParams{Time: 1, Memory: 64, Threads: 1}
+
+func testStorage(t *testing.T) *Storage {
+	t.Helper()
+	dir := t.TempDir()
+	return &Storage{baseDir: dir, dbFile: dir + "/gauth.json", kdf: fastKDF}
+}
+
+var vaultAccounts = []model.Account{{
+	Issuer: "TestIssuer", Label: "test@user", Secret: "JBSWY3DPEHPK3PXP",
+	Digits: 6, Type: model.TypeTOTP, Algorithm: "sha1", Period: 30,
+}}
+
+// encryptLegacy reproduces the headerless layout older versions wrote.
+func encryptLegacy(t *testing.T, data []byte, password string) []byte {
+	t.Helper()
+	salt := bytes.Repeat([]byte{1}, saltLen)
+	nonce := bytes.Repeat([]byte{2}, nonceLen)
+	block, err := aes.NewCipher(legacyKDFParams.deriveKey([]byte(password), salt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := append(append(salt, nonce...), gcm.Seal(nil, nonce, data, nil)...)
+	return out
+}
+
+func TestLegacyVaultIsReadAndUpgraded(t *testing.T) {
+	s := testStorage(t)
+	plain, _ := json.Marshal(vaultAccounts)
+	if err := os.WriteFile(s.dbFile, encryptLegacy(t, plain, "pw"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if enc, _ := s.IsEncrypted(); !enc {
+		t.Error("legacy vault not detected as encrypted")
+	}
+	if _, err := s.ReadAccounts("wrong"); err == nil {
+		t.Error("legacy vault opened with the wrong password")
+	}
+
+	s.kdf = KDFParams{}
+	accounts, err := s.ReadAccounts("pw")
+	if err != nil {
+		t.Fatalf("ReadAccounts() error = %v", err)
+	}
+	if len(accounts) != 1 || accounts[0].Issuer != "TestIssuer" {
+		t.Fatalf("unexpected accounts %+v", accounts)
+	}
+	if s.KDFParams() != DefaultKDFParams {
+		t.Errorf("legacy vault should be upgraded with the default costs, got %v", s.KDFParams())
+	}
+
+	s.kdf = fastKDF
+	if err := s.WriteAccounts(accounts, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(s.dbFile)
+	if !isVault(data) {
+		t.Fatal("rewritten vault has no header")
+	}
+	if _, err := s.ReadAccounts("pw"); err != nil {
+		t.Fatalf("upgraded vault unreadable: %v", err)
+	}
+}
+
+func TestVaultHeaderKeepsKDFParams(t *testing.T) {
+	s := testStorage(t)
+	custom := KDFParams{Time: 2, Memory: 128, Threads: 2}
+	if err := s.SetKDFParams(custom); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteAccounts(vaultAccounts, "pw"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(s.dbFile)
+	h, _, err := parseHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.version != vaultVersion || h.cipherID != CipherAES256GCM || len(h.slots) != 1 {
+		t.Fatalf("unexpected header %+v", h)
+	}
+	if slot := h.slots[0]; slot.kind != SlotPassword || slot.kdfID != KDFArgon2id || slot.kdf != custom {
+		t.Errorf("unexpected key slot %+v", slot)
+	}
+
+	// A fresh Storage learns the costs from the file and keeps them
+	fresh := &Storage{baseDir: s.baseDir, dbFile: s.dbFile}
+	if _, err := fresh.ReadAccounts("pw"); err != nil {
+		t.Fatal(err)
+	}
+	if fresh.KDFParams() != custom {
+		t.Errorf("KDFParams() = %v, want %v", fresh.KDFParams(), custom)
+	}
+}
+
+func TestCorruptedVaultHeader(t *testing.T) {
+	s := testStorage(t)
+	if err := s.WriteAccounts(vaultAccounts, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(s.dbFile)
+
+	tests := map[string][]byte{
+		"truncated":      data[:len(vaultMagic)+4],
+		"future version": append(append(append([]byte{}, vaultMagic...), 99), data[len(vaultMagic)+1:]...),
+		"unknown cipher": append(append(append([]byte{}, data[:len(vaultMagic)+1]...), 42), data[len(vaultMagic)+2:]...),
+	}
+	for name, corrupted := range tests {
+		if err := os.WriteFile(s.dbFile, corrupted, 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := s.ReadAccounts("pw")
+		if err == nil {
+			t.Errorf("%s: ReadAccounts succeeded", name)
+		} else if strings.Contains(err.Error(), "wrong password") {
+			t.Errorf("%s: misleading error %v", name, err)
+		}
+	}
+}
+
+func TestKDFParamsValidate(t *testing.T) {
+	for _, p := range []KDFParams{
+		{Time: 0, Memory: 64, Threads: 1},
+		{Time: 1, Memory: 64, Threads: 0},
+		{Time: 1, Memory: 8, Threads: 4},
+		{Time: 1, Memory: MaxKDFMemory + 1, Threads: 1},
+	} {
+		if p.Validate() == nil {
+			t.Errorf("%+v should be rejected", p)
+		}
+	}
+	if err := DefaultKDFParams.Validate(); err != nil {
+		t.Errorf("default params rejected: %v", err)
+	}
+}
+
+func TestXChaChaVault(t *testing.T) {
+	s := testStorage(t)
+	if err := s.SetCipher(CipherXChaCha20Poly1305); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteAccounts(vaultAccounts, "pw"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(s.dbFile)
+	h, _, err := parseHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.cipherID != CipherXChaCha20Poly1305 || len(h.nonce) != 24 {
+		t.Errorf("cipher %d with a %d byte nonce, want xchacha20-poly1305 with 24", h.cipherID, len(h.nonce))
+	}
+
+	fresh := &Storage{baseDir: s.baseDir, dbFile: s.dbFile}
+	if _, err := fresh.ReadAccounts("pw"); err != nil {
+		t.Fatalf("ReadAccounts() error = %v", err)
+	}
+	if fresh.Cipher() != CipherXChaCha20Poly1305 {
+		t.Errorf("cipher not kept across reads: %s", CipherName(fresh.Cipher()))
+	}
+	if _, err := fresh.ReadAccounts("wrong"); err == nil {
+		t.Error("xchacha vault opened with the wrong password")
+	}
+}
+
+// The header is authenticated, so editing any of it, including a downgrade
+// to the unauthenticated version 1, must fail instead of being accepted.
+func TestVaultHeaderIsAuthenticated(t *testing.T) {
+	s := testStorage(t)
+	if err := s.WriteAccounts(vaultAccounts, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(s.dbFile)
+	h, payload, err := parseHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Adding a slot from another vault must not let its secret in
+	other := testStorage(t)
+	if err := other.WriteAccounts(vaultAccounts, "other"); err != nil {
+		t.Fatal(err)
+	}
+	otherData, _ := os.ReadFile(other.dbFile)
+	oh, _, err := parseHeader(otherData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	downgraded := *h
+	downgraded.cipherID = CipherXChaCha20Poly1305
+	extraSlot := *h
+	extraSlot.slots = append(append([]keySlot{}, h.slots...), oh.slots[0])
+	noNewSlots := *h
+	noNewSlots.slots = append(append([]keySlot{}, h.slots...), h.slots[0])
+
+	for name, header := range map[string]*vaultHeader{"cipher change": &downgraded, "foreign slot": &extraSlot, "duplicated slot": &noNewSlots} {
+		forged := append(header.marshal(), payload...)
+		for _, pw := range []string{"pw", "other"} {
+			if _, err := openVault(forged, credentials{password: pw}); err == nil {
+				t.Errorf("%s: tampered vault opened with %q", name, pw)
+			}
+		}
+	}
+}
+
+// Vaults from before key slots encrypt with the password key directly.
+// Version 1 didn't authenticate its header, version 2 did.
+func TestDirectVaults(t *testing.T) {
+	plain, _ := json.Marshal(vaultAccounts)
+	salt := bytes.Repeat([]byte{3}, saltLen)
+	nonce := bytes.Repeat([]byte{4}, nonceLen)
+	aead, err := newAEAD(CipherAES256GCM, fastKDF.deriveKey([]byte("pw"), salt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []uint8{1, 2} {
+		h := &vaultHeader{version: version, kdfID: KDFArgon2id, kdf: fastKDF, salt: salt, cipherID: CipherAES256GCM, nonce: nonce}
+		header := h.marshal()
+		var aad []byte
+		if version == 2 {
+			aad = header
+		}
+		data := aead.Seal(header, nonce, plain, aad)
+
+		v, err := openVault(data, credentials{password: "pw"})
+		if err != nil {
+			t.Fatalf("v%d: openVault() error = %v", version, err)
+		}
+		if !bytes.Equal(v.plain, plain) || v.header.kdf != fastKDF || v.dataKey != nil {
+			t.Errorf("v%d: unexpected result %s, %+v", version, v.plain, v.header)
+		}
+
+		// Rewriting upgrades to key slots, keeping the costs
+		s := testStorage(t)
+		s.kdf = KDFParams{}
+		if err := os.WriteFile(s.dbFile, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.ReadAccounts("pw"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.WriteAccounts(vaultAccounts, "pw"); err != nil {
+			t.Fatal(err)
+		}
+		upgraded, _ := os.ReadFile(s.dbFile)
+		uh, _, err := parseHeader(upgraded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uh.version != vaultVersion || len(uh.slots) != 1 || uh.slots[0].kdf != fastKDF {
+			t.Errorf("v%d: not upgraded as expected: %+v", version, uh)
+		}
+	}
+}
+
+func TestParseCipher(t *testing.T) {
+	for id, name := range cipherNames {
+		if got, err := ParseCipher(strings.ToUpper(name)); err != nil || got != id {
+			t.Errorf("ParseCipher(%q) = %d, %v", name, got, err)
+		}
+	}
+	if _, err := ParseCipher("rot13"); err == nil {
+		t.Error("ParseCipher accepted an unknown cipher")
+	}
+}
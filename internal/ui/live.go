@@ -8,23 +8,38 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/table"
+	"github.com/leeineian/gauth/internal/clipboard"
 	"github.com/leeineian/gauth/internal/model"
 	"github.com/leeineian/gauth/internal/service"
 )
 
 type tickMsg time.Time
 
+// clearMsg asks the live view to wipe a code it copied earlier.
+type clearMsg struct{ copied *clipboard.Copied }
+
+// SaveFunc persists the full account list after the live view modifies it.
+type SaveFunc func(accounts []model.Account) error
+
 type LiveModel struct {
 	accounts []model.Account
 	otpSvc   *service.OTPService
+	save     SaveFunc
+	cursor   int
+	status   string
 	width    int
 	height   int
+
+	clearAfter time.Duration
+	copied     *clipboard.Copied // code we put on the clipboard and have yet to clear
 }
 
-func NewLiveModel(accounts []model.Account) *LiveModel {
+func NewLiveModel(accounts []model.Account, save SaveFunc, clearAfter time.Duration) *LiveModel {
 	return &LiveModel{
-		accounts: accounts,
-		otpSvc:   service.NewOTPService(),
+		accounts:   accounts,
+		otpSvc:     service.NewOTPService(),
+		save:       save,
+		clearAfter: clearAfter,
 	}
 }
 
@@ -39,7 +54,24 @@ func (m *LiveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "q", "ctrl+c", "esc":
+			m.clearCopied()
 			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.accounts)-1 {
+				m.cursor++
+			}
+		case "n":
+			m.advanceSelected()
+		case "c", "y":
+			return m, m.copySelected()
+		}
+	case clearMsg:
+		if msg.copied == m.copied {
+			m.clearCopied()
 		}
 	case tickMsg:
 		return m, tea.Tick(time.Second, func(t time.Time) tea.Msg {
@@ -52,17 +84,94 @@ func (m *LiveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// advanceSelected bumps the counter of the selected HOTP account and saves
+// the vault, rolling the counter back if the write fails.
+func (m *LiveModel) advanceSelected() {
+	if m.cursor < 0 || m.cursor >= len(m.accounts) {
+		return
+	}
+
+	acc := &m.accounts[m.cursor]
+	if !acc.Type.IsHOTP() {
+		m.status = fmt.Sprintf("%s is not an HOTP account", acc.FullIdentifier())
+		return
+	}
+
+	if _, err := m.otpSvc.Advance(acc); err != nil {
+		m.status = fmt.Sprintf("Error: %v", err)
+		return
+	}
+
+	if m.save != nil {
+		if err := m.save(m.accounts); err != nil {
+			acc.Counter--
+			m.status = fmt.Sprintf("Error: %v", err)
+			return
+		}
+	}
+
+	m.status = fmt.Sprintf("✓ %s advanced to counter %d", acc.FullIdentifier(), acc.Counter)
+}
+
+// copySelected copies the selected code and schedules wiping it.
+func (m *LiveModel) copySelected() tea.Cmd {
+	if m.cursor < 0 || m.cursor >= len(m.accounts) {
+		return nil
+	}
+
+	acc := &m.accounts[m.cursor]
+	res, err := m.otpSvc.Generate(acc)
+	var copied *clipboard.Copied
+	if err == nil {
+		copied, err = clipboard.Copy(res.Code)
+	}
+	if err != nil {
+		m.status = fmt.Sprintf("Error: %v", err)
+		return nil
+	}
+
+	m.copied = copied
+	delay := clipboard.ClearDelay(m.clearAfter, acc, res)
+	if delay == 0 {
+		m.status = fmt.Sprintf("✓ Copied %s", acc.FullIdentifier())
+		return nil
+	}
+
+	m.status = fmt.Sprintf("✓ Copied %s, clearing in %s", acc.FullIdentifier(), delay)
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return clearMsg{copied}
+	})
+}
+
+// clearCopied wipes the code we copied, unless something else has been
+// copied since.
+func (m *LiveModel) clearCopied() {
+	if m.copied == nil || m.clearAfter <= 0 {
+		return
+	}
+	if cleared, err := m.copied.Clear(); err != nil {
+		m.status = fmt.Sprintf("Error: %v", err)
+	} else if cleared {
+		m.status = "✓ Clipboard cleared"
+	}
+	m.copied = nil
+}
+
 func (m *LiveModel) View() string {
 	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true).PaddingRight(1)
 	rowStyle := lipgloss.NewStyle().PaddingRight(1)
+	selectedStyle := rowStyle.Reverse(true)
 
 	tbl := table.New().
 		Border(lipgloss.HiddenBorder()).
 		Headers("ISSUER", "LABEL", "TYPE", "CODE", "REMAINING").
 		StyleFunc(func(row, col int) lipgloss.Style {
-			if row == 0 {
+			if row == table.HeaderRow {
 				return headerStyle
 			}
+			if row == m.cursor {
+				return selectedStyle
+			}
 			return rowStyle
 		})
 
@@ -73,11 +182,11 @@ func (m *LiveModel) View() string {
 
 		if err == nil {
 			codeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Bold(true)
-			if res.Remaining < 5 && acc.Type == model.TypeTOTP {
+			if res.Remaining < 5 && acc.Type.IsTimeBased() {
 				codeStyle = codeStyle.Foreground(lipgloss.Color("9"))
 			}
 			code = codeStyle.Render(res.Code)
-			if acc.Type == model.TypeTOTP {
+			if acc.Type.IsTimeBased() {
 				remaining = fmt.Sprintf("%ds", res.Remaining)
 			}
 		}
@@ -91,11 +200,18 @@ func (m *LiveModel) View() string {
 		)
 	}
 
-	return "\n" + tbl.Render() + "\n\nPress 'q' to exit\n"
+	help := "Press 'q' to exit, ↑/↓ to select, 'c' to copy, 'n' for next HOTP code"
+	if m.status != "" {
+		help = m.status + "\n" + help
+	}
+
+	return "\n" + tbl.Render() + "\n\n" + help + "\n"
 }
 
-func RunLiveView(accounts []model.Account) error {
-	p := tea.NewProgram(NewLiveModel(accounts))
+// RunLiveView shows the live table until the user quits. Codes copied with
+// 'c' are wiped after clearAfter or when they expire; zero keeps them.
+func RunLiveView(accounts []model.Account, save SaveFunc, clearAfter time.Duration) error {
+	p := tea.NewProgram(NewLiveModel(accounts, save, clearAfter))
 	_, err := p.Run()
 	return err
 }
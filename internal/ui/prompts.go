@@ -3,10 +3,13 @@ package ui
 import (
 	"encoding/base32"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/huh"
 	"github.com/leeineian/gauth/internal/model"
+	"github.com/leeineian/gauth/internal/service"
 )
 
 func PromptNewAccount() (*model.Account, error) {
@@ -40,21 +43,7 @@ func PromptNewAccount() (*model.Account, error) {
 				Description("The Base32 secret key").
 				Value(&secret).
 				EchoMode(huh.EchoModePassword).
-				Validate(func(s string) error {
-					s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
-					if s == "" {
-						return fmt.Errorf("secret is required")
-					}
-					_, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
-					if err != nil {
-						// try with padding
-						_, err = base32.StdEncoding.DecodeString(s)
-					}
-					if err != nil {
-						return fmt.Errorf("invalid Base32 secret (standard OTP secrets only use A-Z and 2-7)")
-					}
-					return nil
-				}),
+				Validate(validateSecret),
 		),
 		huh.NewGroup(
 			huh.NewSelect[string]().
@@ -62,14 +51,15 @@ func PromptNewAccount() (*model.Account, error) {
 				Options(
 					huh.NewOption("TOTP (Time-based)", "totp"),
 					huh.NewOption("HOTP (Counter-based)", "hotp"),
+					huh.NewOption("Steam Guard", "steam"),
 				).
 				Value(&otpType),
+		),
+		huh.NewGroup(
 			huh.NewSelect[int]().
 				Title("Digits").
-				Options(
-					huh.NewOption("6 digits", 6),
-					huh.NewOption("8 digits", 8),
-				).
+				Description("Most services use 6 or 8").
+				Options(digitOptions()...).
 				Value(&digits),
 			huh.NewSelect[string]().
 				Title("Algorithm").
@@ -79,7 +69,7 @@ func PromptNewAccount() (*model.Account, error) {
 					huh.NewOption("SHA512", "sha512"),
 				).
 				Value(&algo),
-		),
+		).WithHideFunc(func() bool { return otpType == "steam" }),
 	)
 
 	if err := form.Run(); err != nil {
@@ -88,19 +78,18 @@ func PromptNewAccount() (*model.Account, error) {
 
 	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
 
-	if otpType == "totp" {
+	if otpType == "steam" {
+		// Steam Guard parameters are fixed by the protocol
+		digits = model.SteamDigits
+		algo = model.DefaultAlgo
+		period = model.DefaultPeriod
+	} else if otpType == "totp" {
 		periodStr := "30"
 		err := huh.NewInput().
 			Title("Period").
-			Description("Time window in seconds (usually 30)").
+			Description("Time window in seconds (usually 30 or 60)").
 			Value(&periodStr).
-			Validate(func(s string) error {
-				var v int
-				if _, err := fmt.Sscanf(s, "%d", &v); err != nil || v <= 0 {
-					return fmt.Errorf("must be a positive number")
-				}
-				return nil
-			}).Run()
+			Validate(positiveNumber).Run()
 		if err != nil {
 			return nil, err
 		}
@@ -111,13 +100,7 @@ func PromptNewAccount() (*model.Account, error) {
 			Title("Initial Counter").
 			Description("The starting value for HOTP (usually 0)").
 			Value(&counterStr).
-			Validate(func(s string) error {
-				var v int
-				if _, err := fmt.Sscanf(s, "%d", &v); err != nil || v < 0 {
-					return fmt.Errorf("must be a non-negative number")
-				}
-				return nil
-			}).Run()
+			Validate(nonNegativeNumber).Run()
 		if err != nil {
 			return nil, err
 		}
@@ -136,7 +119,150 @@ func PromptNewAccount() (*model.Account, error) {
 	}, nil
 }
 
-func PromptDeleteAccount(accounts []model.Account) (int, error) {
+// PromptEditAccount shows a form pre-filled with acc and returns the edited
+// copy. The secret field starts empty and the current secret is kept unless
+// a new one is typed in.
+func PromptEditAccount(acc model.Account) (*model.Account, error) {
+	var (
+		issuer  = acc.Issuer
+		label   = acc.DisplayLabel()
+		secret  string
+		otpType = strings.ToLower(string(acc.Type))
+		digits  = acc.Digits
+		algo    = strings.ToLower(acc.Algorithm)
+		period  = strconv.FormatInt(acc.Period, 10)
+		counter = strconv.FormatInt(acc.Counter, 10)
+	)
+	if acc.Period == 0 {
+		period = strconv.Itoa(model.DefaultPeriod)
+	}
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Issuer").
+				Value(&issuer).
+				Validate(required("issuer")),
+			huh.NewInput().
+				Title("Account ID").
+				Value(&label).
+				Validate(required("account ID")),
+			huh.NewInput().
+				Title("Secret").
+				Description("Leave empty to keep the current secret").
+				Value(&secret).
+				EchoMode(huh.EchoModePassword).
+				Validate(func(s string) error {
+					if strings.TrimSpace(s) == "" {
+						return nil
+					}
+					return validateSecret(s)
+				}),
+		),
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Type").
+				Options(
+					huh.NewOption("TOTP (Time-based)", "totp"),
+					huh.NewOption("HOTP (Counter-based)", "hotp"),
+					huh.NewOption("Steam Guard", "steam"),
+				).
+				Value(&otpType),
+		),
+		huh.NewGroup(
+			huh.NewSelect[int]().
+				Title("Digits").
+				Description("Most services use 6 or 8").
+				Options(digitOptions()...).
+				Value(&digits),
+			huh.NewSelect[string]().
+				Title("Algorithm").
+				Options(
+					huh.NewOption("SHA1", "sha1"),
+					huh.NewOption("SHA256", "sha256"),
+					huh.NewOption("SHA512", "sha512"),
+				).
+				Value(&algo),
+		).WithHideFunc(func() bool { return otpType == "steam" }),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Period").
+				Description("Time window in seconds (usually 30 or 60)").
+				Value(&period).
+				Validate(positiveNumber),
+		).WithHideFunc(func() bool { return otpType != "totp" }),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Counter").
+				Description("The next HOTP counter value").
+				Value(&counter).
+				Validate(nonNegativeNumber),
+		).WithHideFunc(func() bool { return otpType != "hotp" }),
+	)
+
+	if err := form.Run(); err != nil {
+		return nil, err
+	}
+
+	acc.Issuer = issuer
+	acc.Label = label
+	if secret = strings.TrimSpace(secret); secret != "" {
+		acc.Secret = secret
+	}
+	acc.Type = model.OTPType(otpType)
+	acc.Digits = digits
+	acc.Algorithm = algo
+	acc.Period, _ = strconv.ParseInt(period, 10, 64)
+	acc.Counter, _ = strconv.ParseInt(counter, 10, 64)
+	return &acc, nil
+}
+
+func validateSecret(s string) error {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	if s == "" {
+		return fmt.Errorf("secret is required")
+	}
+	_, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+	if err != nil {
+		// try with padding
+		_, err = base32.StdEncoding.DecodeString(s)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid Base32 secret (standard OTP secrets only use A-Z and 2-7)")
+	}
+	return nil
+}
+
+func positiveNumber(s string) error {
+	var v int
+	if _, err := fmt.Sscanf(s, "%d", &v); err != nil || v <= 0 {
+		return fmt.Errorf("must be a positive number")
+	}
+	return nil
+}
+
+func nonNegativeNumber(s string) error {
+	var v int
+	if _, err := fmt.Sscanf(s, "%d", &v); err != nil || v < 0 {
+		return fmt.Errorf("must be a non-negative number")
+	}
+	return nil
+}
+
+func digitOptions() []huh.Option[int] {
+	options := make([]huh.Option[int], 0, model.MaxDigits-model.MinDigits+1)
+	for _, d := range []int{6, 8} {
+		options = append(options, huh.NewOption(fmt.Sprintf("%d digits", d), d))
+	}
+	for d := model.MinDigits; d <= model.MaxDigits; d++ {
+		if d != 6 && d != 8 {
+			options = append(options, huh.NewOption(fmt.Sprintf("%d digits (unusual)", d), d))
+		}
+	}
+	return options
+}
+
+func PromptSelectAccount(title string, accounts []model.Account) (int, error) {
 	options := make([]huh.Option[int], 0, len(accounts))
 	for i, acc := range accounts {
 		options = append(options, huh.NewOption(acc.FullIdentifier(), i))
@@ -144,7 +270,7 @@ func PromptDeleteAccount(accounts []model.Account) (int, error) {
 
 	var selected int
 	err := huh.NewSelect[int]().
-		Title("Select account to delete").
+		Title(title).
 		Options(options...).
 		Value(&selected).
 		Run()
@@ -152,6 +278,14 @@ func PromptDeleteAccount(accounts []model.Account) (int, error) {
 	if err != nil {
 		return -1, err
 	}
+	return selected, nil
+}
+
+func PromptDeleteAccount(accounts []model.Account) (int, error) {
+	selected, err := PromptSelectAccount("Select account to delete", accounts)
+	if err != nil {
+		return -1, err
+	}
 
 	var confirm bool
 	err = huh.NewConfirm().
@@ -166,27 +300,111 @@ func PromptDeleteAccount(accounts []model.Account) (int, error) {
 	return selected, nil
 }
 
+// PromptConflict asks how to resolve an imported account that clashes with
+// an existing one. For a rename it also asks for the new label, offering
+// suggested as the default.
+func PromptConflict(entry service.ImportEntry, suggested string) (service.Resolution, string, error) {
+	resolution := service.ResolveKeep
+	err := huh.NewSelect[service.Resolution]().
+		Title(fmt.Sprintf("%s already exists", entry.Account.FullIdentifier())).
+		Description(entry.Detail).
+		Options(
+			huh.NewOption("Keep the existing account", service.ResolveKeep),
+			huh.NewOption("Overwrite it with the imported one", service.ResolveOverwrite),
+			huh.NewOption("Import under a new label", service.ResolveRename),
+			huh.NewOption("Keep both, numbering the imported one's issuer", service.ResolveKeepBoth),
+		).
+		Value(&resolution).
+		Run()
+	if err != nil || resolution != service.ResolveRename {
+		return resolution, "", err
+	}
+
+	label := suggested
+	err = huh.NewInput().
+		Title("New label").
+		Value(&label).
+		Validate(required("label")).
+		Run()
+	return resolution, strings.TrimSpace(label), err
+}
+
+func PromptConfirm(title string) (bool, error) {
+	var confirm bool
+	err := huh.NewConfirm().
+		Title(title).
+		Value(&confirm).
+		Run()
+	return confirm, err
+}
+
+// PromptPassword renders on stderr so commands that print codes keep a
+// clean stdout for scripts.
 func PromptPassword(title string) (string, error) {
+	tty, err := terminalInput()
+	if err != nil {
+		return "", err
+	}
+	defer tty.Close()
+
 	var password string
-	err := huh.NewInput().
+	input := huh.NewInput().
 		Title(title).
 		EchoMode(huh.EchoModePassword).
-		Value(&password).
+		Value(&password)
+	err = huh.NewForm(huh.NewGroup(input)).
+		WithShowHelp(false).
+		WithInput(tty).
+		WithOutput(os.Stderr).
 		Run()
 	return password, err
 }
 
+// terminalInput opens the controlling terminal, so the password is typed
+// there even when stdin carries something else, like --secret-stdin.
+func terminalInput() (*os.File, error) {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return nil, fmt.Errorf("no terminal to prompt for the master password, unlock with --keyfile: %w", err)
+	}
+	return tty, nil
+}
+
 func PromptNewPassword() (string, error) {
+	return promptNewPassword("New Master Password", "Leave empty to remove password protection", true)
+}
+
+// PromptSlotPassword asks for an additional password that unlocks the vault.
+func PromptSlotPassword() (string, error) {
+	return promptNewPassword("Additional Password", "Unlocks the vault alongside the existing ones", false)
+}
+
+// PromptCompositePassword asks for a password that unlocks the vault only
+// together with a keyfile.
+func PromptCompositePassword() (string, error) {
+	return promptNewPassword("Keyfile Password", "Unlocks the vault together with the keyfile", false)
+}
+
+// PromptKeyfilePassword asks for the new master password when a keyfile is
+// in use, where leaving it empty keeps the vault encrypted.
+func PromptKeyfilePassword() (string, error) {
+	return promptNewPassword("New Master Password", "Needed together with the keyfile; leave empty to unlock with the keyfile alone", true)
+}
+
+func promptNewPassword(title, description string, allowEmpty bool) (string, error) {
 	var p1, p2 string
 
 	form := huh.NewForm(
 		huh.NewGroup(
 			huh.NewInput().
-				Title("New Master Password").
-				Description("Leave empty to remove password protection").
+				Title(title).
+				Description(description).
 				EchoMode(huh.EchoModePassword).
 				Value(&p1).
 				Validate(func(s string) error {
+					if len(s) == 0 && !allowEmpty {
+						return fmt.Errorf("password is required")
+					}
 					if len(s) > 0 && len(s) < 8 {
 						return fmt.Errorf("password must be at least 8 characters")
 					}
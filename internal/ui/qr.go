@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"rsc.io/qr"
+)
+
+// quietZone is the light border around the code, in modules. It is narrower
+// than the four modules the spec asks for to keep large batches on screen;
+// phone scanners cope fine with two.
+const quietZone = 2
+
+// RenderQR encodes content as a QR code drawn with Unicode half blocks, so
+// every terminal row holds two rows of modules. Colors are forced to black
+// on white so the code scans on dark terminal themes too.
+func RenderQR(content string) (string, error) {
+	code, err := qr.Encode(content, qr.M)
+	if err != nil {
+		return "", err
+	}
+
+	black := func(x, y int) bool {
+		x, y = x-quietZone, y-quietZone
+		if x < 0 || y < 0 || x >= code.Size || y >= code.Size {
+			return false
+		}
+		return code.Black(x, y)
+	}
+
+	size := code.Size + 2*quietZone
+	var sb strings.Builder
+	for y := 0; y < size; y += 2 {
+		for x := 0; x < size; x++ {
+			top, bottom := black(x, y), black(x, y+1)
+			switch {
+			case top && bottom:
+				sb.WriteString("█")
+			case top:
+				sb.WriteString("▀")
+			case bottom:
+				sb.WriteString("▄")
+			default:
+				sb.WriteString(" ")
+			}
+		}
+		if y+2 < size {
+			sb.WriteByte('\n')
+		}
+	}
+
+	style := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("0")).
+		Background(lipgloss.Color("15"))
+	return style.Render(sb.String()), nil
+}